@@ -2,13 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
 
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/config"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/debugstate"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/health"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	metricssrv "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics/server"
@@ -17,18 +27,35 @@ import (
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/middleware/podexclude"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/middleware/sharedcpuspool"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/middleware/terminalpods"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/middleware/traced"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcetopologyexporter"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/tracing"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/version"
 )
 
+// exit codes, which the oneshot mode callers rely on to tell the failures apart
+const (
+	exitSuccess        = 0
+	exitFailure        = 1
+	exitOneshotScan    = 2
+	exitOneshotPublish = 3
+)
+
 func main() {
+	// the deferred cleanups, most notably the traces flush, must run before exiting
+	os.Exit(run())
+}
+
+func run() int {
+	defer klog.Flush()
 	klog.Infof("starting %s version %s", version.ProgramName, version.Get())
 	defer klog.Infof("stopped %s version %s", version.ProgramName, version.Get())
 
 	parsedArgs, err := config.LoadArgs(os.Args[1:]...)
 	if err != nil {
-		klog.Fatalf("failed to parse args: %v", err)
+		klog.Errorf("failed to parse args: %v", err)
+		return exitFailure
 	}
 
 	if parsedArgs.DumpConfig != "" {
@@ -38,63 +65,140 @@ func main() {
 			fmt.Println(conf)
 		} else if parsedArgs.DumpConfig == ".andexit" {
 			fmt.Println(conf)
-			os.Exit(0)
+			return exitSuccess
 		} else if parsedArgs.DumpConfig == ".log" {
 			klog.Infof("current configuration:\n%s", conf)
 		} else {
 			err = os.WriteFile(parsedArgs.DumpConfig, []byte(conf), 0644)
 			if err != nil {
-				klog.Fatalf("failed to write the config to %q: %v", parsedArgs.DumpConfig, err)
+				klog.Errorf("failed to write the config to %q: %v", parsedArgs.DumpConfig, err)
+				return exitFailure
 			}
 		}
 	}
 
 	if parsedArgs.Version {
 		fmt.Println(version.ProgramName, version.Get())
-		os.Exit(0)
+		return exitSuccess
 	}
 
-	k8scli, err := k8shelpers.GetK8sClient(parsedArgs.Global.KubeConfig)
-	if err != nil {
-		klog.Fatalf("failed to get a kubernetes core client: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	var k8scli kubernetes.Interface
+	var nrtcli topologyclientset.Interface
+	var informerFactory informers.SharedInformerFactory
+	if parsedArgs.NeedsAPIAccess() {
+		k8scli, err = k8shelpers.GetK8sClient(parsedArgs.Global.KubeConfig)
+		if err != nil {
+			klog.Errorf("failed to get a kubernetes core client: %v", err)
+			return exitFailure
+		}
+
+		nrtcli, err = k8shelpers.GetTopologyClient(parsedArgs.Global.KubeConfig)
+		if err != nil {
+			klog.Errorf("failed to get a noderesourcetopology client: %v", err)
+			return exitFailure
+		}
+
+		// all the informers must be node-scoped: every exporter instance watching the whole cluster would be O(nodes^2) load for the apiserver.
+		// The scope is the node we run on, which the resource monitor and the terminal pods filter care about, not the NRT name.
+		informerFactory = k8shelpers.NewNodeScopedInformerFactory(k8scli, k8shelpers.NodeNameFromEnv(parsedArgs.NRTupdater.Hostname), time.Minute)
+	} else {
+		klog.Infof("running without API server access")
 	}
 
-	nrtcli, err := k8shelpers.GetTopologyClient(parsedArgs.Global.KubeConfig)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint: parsedArgs.RTE.TracingEndpoint,
+		Insecure: parsedArgs.RTE.TracingInsecure,
+		NodeName: parsedArgs.NRTupdater.Hostname,
+	})
 	if err != nil {
-		klog.Fatalf("failed to get a noderesourcetopology client: %v", err)
+		klog.Errorf("failed to setup tracing: %v", err)
+		return exitFailure
 	}
+	defer func() {
+		// the signal context is done by now
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			klog.Warningf("failed to flush the traces: %v", err)
+		}
+	}()
 
 	cli, cleanup, err := podres.WaitForReady(podres.GetClient(parsedArgs.RTE.PodResourcesSocketPath))
 	if err != nil {
-		klog.Fatalf("failed to get podresources client: %v", err)
+		klog.Errorf("failed to get podresources client: %v", err)
+		return exitFailure
 	}
 	defer cleanup()
 
+	cli = traced.NewFromLister(cli, traced.LayerKubelet)
+
 	cli = sharedcpuspool.NewFromLister(cli, parsedArgs.Global.Debug, parsedArgs.RTE.ReferenceContainer)
+	cli = traced.NewFromLister(cli, "sharedcpuspool")
 
 	if len(parsedArgs.Resourcemonitor.PodExclude) > 0 {
 		cli = podexclude.NewFromLister(cli, parsedArgs.Global.Debug, parsedArgs.Resourcemonitor.PodExclude)
+		cli = traced.NewFromLister(cli, "podexclude")
 	}
 
 	if parsedArgs.Resourcemonitor.ExcludeTerminalPods {
 		klog.Infof("terminal pods are filtered from the PodResourcesLister client")
-		cli, err = terminalpods.NewFromLister(context.TODO(), cli, k8scli, time.Minute, parsedArgs.Global.Debug)
+		cli, err = terminalpods.NewFromLister(ctx, cli, informerFactory, parsedArgs.Global.Debug)
 		if err != nil {
-			klog.Fatalf("failed to get PodResourceAPI client: %v", err)
+			klog.Errorf("failed to get PodResourceAPI client: %v", err)
+			return exitFailure
 		}
+		cli = traced.NewFromLister(cli, "terminalpods")
 	}
 
-	err = metrics.Setup("")
+	nodeName, err := metrics.ResolveNodeName("")
 	if err != nil {
-		klog.Fatalf("failed to setup metrics: %v", err)
+		klog.Errorf("failed to setup metrics: %v", err)
+		return exitFailure
 	}
-	err = metricssrv.Setup(parsedArgs.RTE.MetricsMode, metricssrv.NewConfig(parsedArgs.RTE.MetricsAddress, parsedArgs.RTE.MetricsPort, parsedArgs.RTE.MetricsTLSCfg))
+	buckets, err := metrics.ParseLatencyBuckets(parsedArgs.RTE.MetricsLatencyBuckets)
 	if err != nil {
-		klog.Fatalf("failed to setup metrics server: %v", err)
+		klog.Errorf("failed to parse the latency buckets: %v", err)
+		return exitFailure
 	}
+	// served by the metrics server
+	rteMetrics, err := metrics.New(nodeName, ctrlmetrics.Registry, metrics.WithLatencyBuckets(buckets))
+	if err != nil {
+		klog.Errorf("failed to setup metrics: %v", err)
+		return exitFailure
+	}
+	tracker := health.NewPipelineTracker()
+	var dbg *debugstate.State
+	if parsedArgs.RTE.DebugEndpoint {
+		dbg = debugstate.New()
+	}
+	metricsConf := metricssrv.NewConfig(parsedArgs.RTE.MetricsAddress, parsedArgs.RTE.MetricsPort, parsedArgs.RTE.MetricsTLSCfg)
+	metricsConf.Debug = dbg
+	metricsConf.Metrics = rteMetrics
+	if parsedArgs.RTE.MetricsMode == metricssrv.ServingHTTPTLSAuth {
+		metricsConf.RestConfig, err = k8shelpers.GetRestConfig(parsedArgs.Global.KubeConfig)
+		if err != nil {
+			klog.Errorf("failed to get the client configuration for the metrics server: %v", err)
+			return exitFailure
+		}
+	}
+	err = metricssrv.Setup(parsedArgs.RTE.MetricsMode, metricsConf)
+	if err != nil {
+		klog.Errorf("failed to setup metrics server: %v", err)
+		return exitFailure
+	}
+	if parsedArgs.RTE.HealthAddress != "" {
+		err = health.Serve(ctx, parsedArgs.RTE.HealthAddress, tracker)
+		if err != nil {
+			klog.Errorf("failed to setup the health endpoints: %v", err)
+			return exitFailure
+		}
+	}
+	dbg.Record(debugstate.KeyConfig, parsedArgs)
 
 	if parsedArgs.Resourcemonitor.PodSetFingerprint {
-		ctx := context.TODO()
 		hnd := pfpdump.Handle{
 			Dumpfile: parsedArgs.Resourcemonitor.PodSetFingerprintStatusFile,
 		}
@@ -105,11 +209,28 @@ func main() {
 		ResMon: resourcemonitor.Handle{
 			PodResCli: cli,
 			K8SCli:    k8scli,
+			Informers: informerFactory,
+			Metrics:   rteMetrics,
+			Health:    tracker,
+			Debug:     dbg,
 		},
 		NRTCli: nrtcli,
 	}
-	err = resourcetopologyexporter.Execute(hnd, parsedArgs.NRTupdater, parsedArgs.Resourcemonitor, parsedArgs.RTE)
+	err = resourcetopologyexporter.Execute(ctx, hnd, parsedArgs.NRTupdater, parsedArgs.Resourcemonitor, parsedArgs.RTE)
 	if err != nil {
-		klog.Fatalf("failed to execute: %v", err)
+		klog.Errorf("failed to execute: %v", err)
+		return exitCodeFor(err)
+	}
+	return exitSuccess
+}
+
+func exitCodeFor(err error) int {
+	switch {
+	case errors.Is(err, resourcetopologyexporter.ErrOneshotScan):
+		return exitOneshotScan
+	case errors.Is(err, resourcetopologyexporter.ErrOneshotPublish):
+		return exitOneshotPublish
+	default:
+		return exitFailure
 	}
 }
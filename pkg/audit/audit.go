@@ -0,0 +1,199 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+const (
+	DefaultMaxSizeBytes = 10 * 1024 * 1024
+	DefaultMaxBackups   = 3
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDryRun  = "dry-run"
+	// OutcomeQueued is recorded for the data accepted by a publisher which defers the delivery, e.g. batching it.
+	OutcomeQueued = "queued"
+)
+
+// Entry is a record of the audit log, serialized as a single JSON line.
+type Entry struct {
+	Timestamp      time.Time   `json:"timestamp"`
+	Node           string      `json:"node"`
+	Trigger        string      `json:"trigger"`
+	PodFingerprint string      `json:"podFingerprint,omitempty"`
+	Zones          []ZoneDelta `json:"zones,omitempty"`
+	Outcome        string      `json:"outcome"`
+	Error          string      `json:"error,omitempty"`
+}
+
+type ZoneDelta struct {
+	Name      string          `json:"name"`
+	Resources []ResourceDelta `json:"resources"`
+}
+
+// ResourceDelta reports the available amount of a resource, and its change since the previous record.
+// The change is omitted for the resources not reported in the previous record.
+type ResourceDelta struct {
+	Name      string `json:"name"`
+	Available string `json:"available"`
+	Delta     string `json:"delta,omitempty"`
+}
+
+// Log appends the entries to a file, rotating it when it exceeds the maximum size.
+// The rotated files are named after the log file with a numeric suffix, ".1" being the most recent.
+type Log struct {
+	path         string
+	maxSizeBytes int64
+	maxBackups   int
+
+	lock      sync.Mutex
+	prevZones v1alpha2.ZoneList
+}
+
+// New creates a Log writing into path. Non-positive limits select the defaults.
+func New(path string, maxSizeBytes int64, maxBackups int) *Log {
+	if maxSizeBytes <= 0 {
+		maxSizeBytes = DefaultMaxSizeBytes
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+	return &Log{
+		path:         path,
+		maxSizeBytes: maxSizeBytes,
+		maxBackups:   maxBackups,
+	}
+}
+
+// Record appends an entry, computing the zone deltas against the zones of the previous call.
+func (al *Log) Record(ts time.Time, node, trigger, podFingerprint string, zones v1alpha2.ZoneList, outcome string, err error) error {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	entry := Entry{
+		Timestamp:      ts,
+		Node:           node,
+		Trigger:        trigger,
+		PodFingerprint: podFingerprint,
+		Zones:          ZoneDeltas(al.prevZones, zones),
+		Outcome:        outcome,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	al.prevZones = zones.DeepCopy()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return al.write(data)
+}
+
+func (al *Log) write(data []byte) error {
+	info, err := os.Stat(al.path)
+	if err == nil && info.Size() > 0 && info.Size()+int64(len(data)) > al.maxSizeBytes {
+		err = al.rotate()
+		if err != nil {
+			return fmt.Errorf("cannot rotate audit log %q: %w", al.path, err)
+		}
+	}
+
+	dst, err := os.OpenFile(al.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(data)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func (al *Log) rotate() error {
+	err := os.Remove(backupName(al.path, al.maxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for idx := al.maxBackups - 1; idx >= 1; idx-- {
+		err = os.Rename(backupName(al.path, idx), backupName(al.path, idx+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(al.path, backupName(al.path, 1))
+}
+
+func backupName(path string, idx int) string {
+	return fmt.Sprintf("%s.%d", path, idx)
+}
+
+// ZoneDeltas reports the available resources per zone, and their changes from prev to curr.
+func ZoneDeltas(prev, curr v1alpha2.ZoneList) []ZoneDelta {
+	prevAvail := make(map[string]map[string]resource.Quantity)
+	for _, zone := range prev {
+		prevAvail[zone.Name] = availableByName(zone.Resources)
+	}
+
+	var deltas []ZoneDelta
+	for _, zone := range curr {
+		zd := ZoneDelta{
+			Name: zone.Name,
+		}
+		prevRes, zoneSeen := prevAvail[zone.Name]
+		for _, res := range zone.Resources {
+			rd := ResourceDelta{
+				Name:      res.Name,
+				Available: res.Available.String(),
+			}
+			if prevQty, ok := prevRes[res.Name]; zoneSeen && ok {
+				delta := res.Available.DeepCopy()
+				delta.Sub(prevQty)
+				rd.Delta = delta.String()
+			}
+			zd.Resources = append(zd.Resources, rd)
+		}
+		sort.Slice(zd.Resources, func(i, j int) bool {
+			return zd.Resources[i].Name < zd.Resources[j].Name
+		})
+		deltas = append(deltas, zd)
+	}
+	return deltas
+}
+
+func availableByName(resources v1alpha2.ResourceInfoList) map[string]resource.Quantity {
+	ret := make(map[string]resource.Quantity, len(resources))
+	for _, res := range resources {
+		ret[res.Name] = res.Available
+	}
+	return ret
+}
@@ -0,0 +1,181 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+func TestZoneDeltas(t *testing.T) {
+	type testCase struct {
+		name     string
+		prev     v1alpha2.ZoneList
+		curr     v1alpha2.ZoneList
+		expected []ZoneDelta
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name: "no previous data",
+			curr: v1alpha2.ZoneList{makeZone("node-0", "cpu", "4")},
+			expected: []ZoneDelta{
+				{Name: "node-0", Resources: []ResourceDelta{{Name: "cpu", Available: "4"}}},
+			},
+		},
+		{
+			name: "changes",
+			prev: v1alpha2.ZoneList{
+				makeZone("node-0", "cpu", "4"),
+				makeZone("node-1", "memory", "2Gi"),
+			},
+			curr: v1alpha2.ZoneList{
+				makeZone("node-0", "cpu", "2"),
+				makeZone("node-1", "memory", "3Gi"),
+			},
+			expected: []ZoneDelta{
+				{Name: "node-0", Resources: []ResourceDelta{{Name: "cpu", Available: "2", Delta: "-2"}}},
+				{Name: "node-1", Resources: []ResourceDelta{{Name: "memory", Available: "3Gi", Delta: "1Gi"}}},
+			},
+		},
+		{
+			name: "new resource",
+			prev: v1alpha2.ZoneList{makeZone("node-0", "cpu", "4")},
+			curr: v1alpha2.ZoneList{
+				{
+					Name: "node-0",
+					Resources: v1alpha2.ResourceInfoList{
+						{Name: "hugepages-2Mi", Available: resource.MustParse("16Mi")},
+						{Name: "cpu", Available: resource.MustParse("4")},
+					},
+				},
+			},
+			expected: []ZoneDelta{
+				{Name: "node-0", Resources: []ResourceDelta{
+					{Name: "cpu", Available: "4", Delta: "0"},
+					{Name: "hugepages-2Mi", Available: "16Mi"},
+				}},
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := ZoneDeltas(tcase.prev, tcase.curr)
+			if !reflect.DeepEqual(got, tcase.expected) {
+				t.Errorf("deltas mismatch:\ngot      %+v\nexpected %+v", got, tcase.expected)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	al := New(path, 0, 0)
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := al.Record(ts, "node-a", "periodic", "pfp0v0011223344", v1alpha2.ZoneList{makeZone("node-0", "cpu", "4")}, OutcomeSuccess, nil)
+	if err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	err = al.Record(ts, "node-a", "reactive", "pfp0v0055667788", v1alpha2.ZoneList{makeZone("node-0", "cpu", "3")}, OutcomeFailure, errors.New("boom"))
+	if err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	entries := readEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if entries[0].Trigger != "periodic" || entries[0].Outcome != OutcomeSuccess || entries[0].Error != "" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].PodFingerprint != "pfp0v0055667788" || entries[1].Outcome != OutcomeFailure || entries[1].Error != "boom" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+	if got := entries[1].Zones[0].Resources[0].Delta; got != "-1" {
+		t.Errorf("unexpected delta: %q", got)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	maxBackups := 2
+	// each entry is larger than this, so every record after the first rotates
+	al := New(path, 16, maxBackups)
+
+	ts := time.Now()
+	for _, trigger := range []string{"t0", "t1", "t2", "t3"} {
+		err := al.Record(ts, "node-a", trigger, "", nil, OutcomeSuccess, nil)
+		if err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:                "t3",
+		backupName(path, 1): "t2",
+		backupName(path, 2): "t1",
+	}
+	for name, trigger := range expected {
+		entries := readEntries(t, name)
+		if len(entries) != 1 || entries[0].Trigger != trigger {
+			t.Errorf("unexpected content of %q: %+v", name, entries)
+		}
+	}
+	if _, err := os.Stat(backupName(path, maxBackups+1)); !os.IsNotExist(err) {
+		t.Errorf("too many backups kept: %v", err)
+	}
+}
+
+func makeZone(name, resName, avail string) v1alpha2.Zone {
+	return v1alpha2.Zone{
+		Name: name,
+		Type: "Node",
+		Resources: v1alpha2.ResourceInfoList{
+			{Name: resName, Available: resource.MustParse(avail)},
+		},
+	}
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	src, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %q: %v", path, err)
+	}
+	defer src.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		var entry Entry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -110,6 +110,25 @@ func dispatchConfObj(obj map[string]interface{}, pArgs *ProgArgs) error {
 		{key: "nrtUpdater.hostname", out: &pArgs.NRTupdater.Hostname},
 		{key: "nrtUpdater.patchMode", out: &pArgs.NRTupdater.PatchMode},
 		{key: "nrtUpdater.patchResync", out: &pArgs.NRTupdater.PatchResync},
+		{key: "nrtUpdater.staleCleanup", out: &pArgs.NRTupdater.StaleCleanup},
+		{key: "nrtUpdater.nodeLabels", out: &pArgs.NRTupdater.NodeLabels},
+		{key: "nrtUpdater.nodeAnnotations", out: &pArgs.NRTupdater.NodeAnnotations},
+		{key: "nrtUpdater.publisher", out: &pArgs.NRTupdater.Publisher},
+		{key: "nrtUpdater.publisherNamespace", out: &pArgs.NRTupdater.PublisherNamespace},
+		{key: "nrtUpdater.publisherFile", out: &pArgs.NRTupdater.PublisherFile},
+		{key: "nrtUpdater.publisherFormat", out: &pArgs.NRTupdater.PublisherFormat},
+		{key: "nrtUpdater.publisherHistory", out: &pArgs.NRTupdater.PublisherHistory},
+		{key: "nrtUpdater.webhookURL", out: &pArgs.NRTupdater.WebhookURL},
+		{key: "nrtUpdater.webhookPayload", out: &pArgs.NRTupdater.WebhookPayload},
+		{key: "nrtUpdater.webhookCAFile", out: &pArgs.NRTupdater.WebhookCAFile},
+		{key: "nrtUpdater.webhookCertFile", out: &pArgs.NRTupdater.WebhookCertFile},
+		{key: "nrtUpdater.webhookKeyFile", out: &pArgs.NRTupdater.WebhookKeyFile},
+		{key: "nrtUpdater.webhookRetries", out: &pArgs.NRTupdater.WebhookRetries},
+		{key: "nrtUpdater.webhookBatchSize", out: &pArgs.NRTupdater.WebhookBatchSize},
+		{key: "nrtUpdater.webhookBatchInterval", out: &pArgs.NRTupdater.WebhookBatchInterval},
+		{key: "nrtUpdater.auditLogFile", out: &pArgs.NRTupdater.AuditLogFile},
+		{key: "nrtUpdater.auditLogMaxSizeMB", out: &pArgs.NRTupdater.AuditLogMaxSizeMB},
+		{key: "nrtUpdater.auditLogMaxBackups", out: &pArgs.NRTupdater.AuditLogMaxBackups},
 		{key: "resourceMonitor.namespace", out: &pArgs.Resourcemonitor.Namespace},
 		{key: "resourceMonitor.sysfsRoot", out: &pArgs.Resourcemonitor.SysfsRoot},
 		{key: "resourceMonitor.refreshNodeResources", out: &pArgs.Resourcemonitor.RefreshNodeResources},
@@ -118,19 +137,34 @@ func dispatchConfObj(obj map[string]interface{}, pArgs *ProgArgs) error {
 		{key: "resourceMonitor.exposeTiming", out: &pArgs.Resourcemonitor.ExposeTiming},
 		{key: "resourceMonitor.podSetFingerprintStatusFile", out: &pArgs.Resourcemonitor.PodSetFingerprintStatusFile},
 		{key: "resourceMonitor.excludeTerminalPods", out: &pArgs.Resourcemonitor.ExcludeTerminalPods},
+		{key: "resourceMonitor.podAllocationMetrics", out: &pArgs.Resourcemonitor.PodAllocationMetrics},
+		{key: "resourceMonitor.podAllocationMetricsMaxSeries", out: &pArgs.Resourcemonitor.PodAllocationMetricsMaxSeries},
+		{key: "resourceMonitor.fragmentation", out: &pArgs.Resourcemonitor.Fragmentation},
 		{key: "topologyExporter.podResourcesSocketPath", out: &pArgs.RTE.PodResourcesSocketPath},
 		{key: "topologyExporter.sleepInterval", out: &pArgs.RTE.SleepInterval},
 		{key: "topologyExporter.podReadinessEnable", out: &pArgs.RTE.PodReadinessEnable},
+		{key: "topologyExporter.leaseEnable", out: &pArgs.RTE.LeaseEnable},
+		{key: "topologyExporter.leaseNamespace", out: &pArgs.RTE.LeaseNamespace},
+		{key: "topologyExporter.leaseDuration", out: &pArgs.RTE.LeaseDuration},
+		{key: "topologyExporter.eventsEnable", out: &pArgs.RTE.EventsEnable},
 		{key: "topologyExporter.notifyFilePath", out: &pArgs.RTE.NotifyFilePath},
 		{key: "topologyExporter.timeUnitToLimitEvents", out: &pArgs.RTE.TimeUnitToLimitEvents},
 		{key: "topologyExporter.addNRTOwnerEnable", out: &pArgs.RTE.AddNRTOwnerEnable},
 		{key: "topologyExporter.metricsMode", out: &pArgs.RTE.MetricsMode},
 		{key: "topologyExporter.metricsPort", out: &pArgs.RTE.MetricsPort},
 		{key: "topologyExporter.MetricsAddress", out: &pArgs.RTE.MetricsAddress},
+		{key: "topologyExporter.metricsLatencyBuckets", out: &pArgs.RTE.MetricsLatencyBuckets},
+		{key: "topologyExporter.healthStaleThreshold", out: &pArgs.RTE.HealthStaleThreshold},
+		{key: "topologyExporter.healthAddress", out: &pArgs.RTE.HealthAddress},
+		{key: "topologyExporter.debugEndpoint", out: &pArgs.RTE.DebugEndpoint},
+		{key: "topologyExporter.tracingEndpoint", out: &pArgs.RTE.TracingEndpoint},
+		{key: "topologyExporter.tracingInsecure", out: &pArgs.RTE.TracingInsecure},
 		{key: "topologyExporter.metricsTLS.certsDir", out: &pArgs.RTE.MetricsTLSCfg.CertsDir},
 		{key: "topologyExporter.metricsTLS.certFile", out: &pArgs.RTE.MetricsTLSCfg.CertFile},
 		{key: "topologyExporter.metricsTLS.keyFile", out: &pArgs.RTE.MetricsTLSCfg.KeyFile},
 		{key: "topologyExporter.metricsTLS.wantCliAuth", out: &pArgs.RTE.MetricsTLSCfg.WantCliAuth},
+		{key: "topologyExporter.metricsTLS.clientCAFile", out: &pArgs.RTE.MetricsTLSCfg.ClientCAFile},
+		{key: "topologyExporter.metricsTLS.allowedSubjects", out: &pArgs.RTE.MetricsTLSCfg.AllowedSubjects},
 		{key: "topologyExporter.metricsTLS.minTLSVersion", out: &pArgs.RTE.MetricsTLSCfg.MinTLSVersion},
 		{key: "topologyExporter.metricsTLS.cipherSuites", out: &pArgs.RTE.MetricsTLSCfg.CipherSuites},
 	}
@@ -86,6 +86,22 @@ func (pa ProgArgs) Clone() ProgArgs {
 	}
 }
 
+// NeedsAPIAccess tells if the configuration requires to talk to the Kubernetes API server.
+// This is not the case if the data is sent to a destination outside the cluster and all the features
+// which read or write cluster objects are disabled.
+func (pa *ProgArgs) NeedsAPIAccess() bool {
+	if nrtupdater.PublisherNeedsAPI(pa.NRTupdater.Publisher) {
+		return true
+	}
+	if pa.NRTupdater.NodeLabels != "" || pa.NRTupdater.NodeAnnotations != "" {
+		return true
+	}
+	if pa.Resourcemonitor.RefreshNodeResources || pa.Resourcemonitor.ExcludeTerminalPods {
+		return true
+	}
+	return pa.RTE.PodReadinessEnable || pa.RTE.AddNRTOwnerEnable || pa.RTE.LeaseEnable || pa.RTE.EventsEnable
+}
+
 // The args is passed only for testing purposes.
 func LoadArgs(args ...string) (ProgArgs, error) {
 	var err error
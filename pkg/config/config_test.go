@@ -24,6 +24,8 @@ import (
 	"testing"
 
 	"sigs.k8s.io/yaml"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
 )
 
 func TestLoadArgs(t *testing.T) {
@@ -74,6 +76,72 @@ func TestLoadArgs(t *testing.T) {
 	}
 }
 
+func TestNeedsAPIAccess(t *testing.T) {
+	type testCase struct {
+		name     string
+		mutate   func(pArgs *ProgArgs)
+		expected bool
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:     "NRT publisher",
+			mutate:   func(pArgs *ProgArgs) {},
+			expected: true,
+		},
+		{
+			name: "file publisher",
+			mutate: func(pArgs *ProgArgs) {
+				pArgs.NRTupdater.Publisher = nrtupdater.PublisherFile
+			},
+			expected: false,
+		},
+		{
+			name: "webhook publisher",
+			mutate: func(pArgs *ProgArgs) {
+				pArgs.NRTupdater.Publisher = nrtupdater.PublisherWebhook
+			},
+			expected: false,
+		},
+		{
+			name: "configmap publisher",
+			mutate: func(pArgs *ProgArgs) {
+				pArgs.NRTupdater.Publisher = nrtupdater.PublisherConfigMap
+			},
+			expected: true,
+		},
+		{
+			name: "file publisher with pod readiness",
+			mutate: func(pArgs *ProgArgs) {
+				pArgs.NRTupdater.Publisher = nrtupdater.PublisherFile
+				pArgs.RTE.PodReadinessEnable = true
+			},
+			expected: true,
+		},
+		{
+			name: "file publisher with node labels",
+			mutate: func(pArgs *ProgArgs) {
+				pArgs.NRTupdater.Publisher = nrtupdater.PublisherFile
+				pArgs.NRTupdater.NodeLabels = "topology.kubernetes.io/*"
+			},
+			expected: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			var pArgs ProgArgs
+			SetDefaults(&pArgs)
+			// enabled by default, and they need API access
+			pArgs.RTE.PodReadinessEnable = false
+			pArgs.RTE.AddNRTOwnerEnable = false
+			tcase.mutate(&pArgs)
+			got := pArgs.NeedsAPIAccess()
+			if got != tcase.expected {
+				t.Errorf("got %v expected %v", got, tcase.expected)
+			}
+		})
+	}
+}
+
 func TestUserHomeDirWithoutEnv(t *testing.T) {
 	t.Setenv("HOME", "")
 	_, err := UserHomeDir()
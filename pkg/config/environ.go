@@ -73,6 +73,9 @@ func FromEnv(pArgs *ProgArgs) {
 	if pArgs.RTE.MetricsAddress == "" {
 		pArgs.RTE.MetricsAddress = metricssrv.AddressFromEnv()
 	}
+	if pArgs.RTE.MetricsTLSCfg.ClientCAFile == "" {
+		pArgs.RTE.MetricsTLSCfg.ClientCAFile = metricssrv.ClientCAFileFromEnv()
+	}
 	if pArgs.Resourcemonitor.PodSetFingerprintStatusFile == "" {
 		pArgs.Resourcemonitor.PodSetFingerprintStatusFile = PodSetFingerprintStatusFileFromEnv()
 	}
@@ -22,7 +22,9 @@ import (
 
 	"k8s.io/klog/v2"
 
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	metricssrv "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics/server"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/middleware/sharedcpuspool"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/version"
@@ -49,10 +51,29 @@ func FromFlags(pArgs *ProgArgs, args ...string) (string, string, error) {
 	CommandLine.BoolVar(&pArgs.Global.Debug, "debug", pArgs.Global.Debug, " Enable debug output.")
 	CommandLine.StringVar(&pArgs.Global.KubeConfig, "kubeconfig", pArgs.Global.KubeConfig, "path to kubeconfig file.")
 
-	CommandLine.BoolVar(&pArgs.NRTupdater.NoPublish, "no-publish", pArgs.NRTupdater.NoPublish, "Do not publish discovered features to the cluster-local Kubernetes API server.")
-	CommandLine.BoolVar(&pArgs.NRTupdater.Oneshot, "oneshot", pArgs.NRTupdater.Oneshot, "Update once and exit.")
+	CommandLine.BoolVar(&pArgs.NRTupdater.NoPublish, "no-publish", pArgs.NRTupdater.NoPublish, "Do not publish discovered features to the cluster-local Kubernetes API server. Log the differences between the computed objects instead.")
+	CommandLine.BoolVar(&pArgs.NRTupdater.Oneshot, "oneshot", pArgs.NRTupdater.Oneshot, "Update once and exit. The exit code is 0 on success, 2 if the scan failed, 3 if the publish failed and 1 on any other error.")
 	CommandLine.StringVar(&pArgs.NRTupdater.Hostname, "hostname", pArgs.NRTupdater.Hostname, "Override the node hostname.")
 	CommandLine.BoolVar(&pArgs.NRTupdater.PatchMode, "patch-mode", pArgs.NRTupdater.PatchMode, "Send updates using patches.")
+	CommandLine.StringVar(&pArgs.NRTupdater.StaleCleanup, "stale-nrt-cleanup", pArgs.NRTupdater.StaleCleanup, fmt.Sprintf("What to do at startup with the NRT objects previously written by this node under a different name. Valid options: %s. Empty means disabled.", nrtupdater.StaleCleanupSupported()))
+	CommandLine.StringVar(&pArgs.NRTupdater.NodeLabels, "nrt-node-labels", pArgs.NRTupdater.NodeLabels, "Comma-separated glob patterns of the Node labels to mirror onto the NRT object.")
+	CommandLine.StringVar(&pArgs.NRTupdater.NodeAnnotations, "nrt-node-annotations", pArgs.NRTupdater.NodeAnnotations, "Comma-separated glob patterns of the Node annotations to mirror onto the NRT object.")
+	CommandLine.StringVar(&pArgs.NRTupdater.Publisher, "publisher", pArgs.NRTupdater.Publisher, fmt.Sprintf("Select where to publish the topology data. Valid options: %s. Empty means the NRT API. The file and webhook publishers don't need API access, unless other features using it, like the pod readiness or the NRT owner, are enabled.", nrtupdater.PublisherSupported()))
+	CommandLine.StringVar(&pArgs.NRTupdater.PublisherNamespace, "publisher-namespace", pArgs.NRTupdater.PublisherNamespace, "Namespace of the ConfigMaps written by the configmap publisher. Empty means the exporter pod namespace, from the REFERENCE_NAMESPACE env var.")
+	CommandLine.StringVar(&pArgs.NRTupdater.PublisherFile, "publisher-file", pArgs.NRTupdater.PublisherFile, "Destination file of the file publisher. Use \"-\" or empty for stdout.")
+	CommandLine.StringVar(&pArgs.NRTupdater.PublisherFormat, "publisher-format", pArgs.NRTupdater.PublisherFormat, fmt.Sprintf("Serialization format of the file publisher. Valid options: %s. Empty means json.", nrtupdater.FileFormatSupported()))
+	CommandLine.BoolVar(&pArgs.NRTupdater.PublisherHistory, "publisher-history", pArgs.NRTupdater.PublisherHistory, "If enable, the file publisher appends JSON lines instead of replacing the file content.")
+	CommandLine.StringVar(&pArgs.NRTupdater.WebhookURL, "webhook-url", pArgs.NRTupdater.WebhookURL, "URL to POST the topology updates to. If the webhook is not the selected publisher, it receives the updates alongside it.")
+	CommandLine.StringVar(&pArgs.NRTupdater.WebhookPayload, "webhook-payload", pArgs.NRTupdater.WebhookPayload, fmt.Sprintf("Payload of the webhook updates. Valid options: %s. Empty means object.", nrtupdater.WebhookPayloadSupported()))
+	CommandLine.StringVar(&pArgs.NRTupdater.WebhookCAFile, "webhook-ca-file", pArgs.NRTupdater.WebhookCAFile, "CA bundle to verify the webhook server certificate. Empty uses the system roots.")
+	CommandLine.StringVar(&pArgs.NRTupdater.WebhookCertFile, "webhook-cert-file", pArgs.NRTupdater.WebhookCertFile, "Client certificate file for the webhook mTLS authentication.")
+	CommandLine.StringVar(&pArgs.NRTupdater.WebhookKeyFile, "webhook-key-file", pArgs.NRTupdater.WebhookKeyFile, "Client key file for the webhook mTLS authentication.")
+	CommandLine.IntVar(&pArgs.NRTupdater.WebhookRetries, "webhook-retries", pArgs.NRTupdater.WebhookRetries, "Retry a failed webhook request up to N times, with exponential backoff.")
+	CommandLine.IntVar(&pArgs.NRTupdater.WebhookBatchSize, "webhook-batch-size", pArgs.NRTupdater.WebhookBatchSize, "Send the webhook updates in batches of up to N items. 0 or 1 means no batching.")
+	CommandLine.DurationVar(&pArgs.NRTupdater.WebhookBatchInterval, "webhook-batch-interval", pArgs.NRTupdater.WebhookBatchInterval, "Send an incomplete batch of webhook updates after this interval. 0 means wait for the batch to be full.")
+	CommandLine.StringVar(&pArgs.NRTupdater.AuditLogFile, "audit-log-file", pArgs.NRTupdater.AuditLogFile, "File to record the JSON-lines audit log of the topology publishes. Use empty string to disable.")
+	CommandLine.IntVar(&pArgs.NRTupdater.AuditLogMaxSizeMB, "audit-log-max-size", pArgs.NRTupdater.AuditLogMaxSizeMB, "Rotate the audit log when it exceeds this size, in megabytes. 0 means the default (10).")
+	CommandLine.IntVar(&pArgs.NRTupdater.AuditLogMaxBackups, "audit-log-max-backups", pArgs.NRTupdater.AuditLogMaxBackups, "Number of rotated audit log files to keep. 0 means the default (3).")
 	CommandLine.IntVar(&pArgs.NRTupdater.PatchResync, "patch-resync", pArgs.NRTupdater.PatchResync, "Force a full get+update resync every N patch cycles. 0 means never resync.")
 
 	CommandLine.StringVar(&pArgs.Resourcemonitor.Namespace, "watch-namespace", pArgs.Resourcemonitor.Namespace, "Namespace to watch pods for. Use \"\" for all namespaces.")
@@ -62,6 +83,9 @@ func FromFlags(pArgs *ProgArgs, args ...string) (string, string, error) {
 	CommandLine.BoolVar(&pArgs.Resourcemonitor.RefreshNodeResources, "refresh-node-resources", pArgs.Resourcemonitor.RefreshNodeResources, "If enable, track changes in node's resources")
 	CommandLine.StringVar(&pArgs.Resourcemonitor.PodSetFingerprintStatusFile, "pods-fingerprint-status-file", pArgs.Resourcemonitor.PodSetFingerprintStatusFile, "File to dump the pods fingerprint status. Use empty string to disable.")
 	CommandLine.BoolVar(&pArgs.Resourcemonitor.ExcludeTerminalPods, "exclude-terminal-pods", pArgs.Resourcemonitor.ExcludeTerminalPods, "If enable, exclude terminal pods from podresource API List call")
+	CommandLine.BoolVar(&pArgs.Resourcemonitor.PodAllocationMetrics, "pod-allocation-metrics", pArgs.Resourcemonitor.PodAllocationMetrics, "If enable, export the resources exclusively allocated to each container, per NUMA zone, as metrics.")
+	CommandLine.IntVar(&pArgs.Resourcemonitor.PodAllocationMetricsMaxSeries, "pod-allocation-metrics-max-series", pArgs.Resourcemonitor.PodAllocationMetricsMaxSeries, fmt.Sprintf("Max number of per-container allocation series to export. 0 means the default (%d).", metrics.DefaultPodAllocationMaxSeries))
+	CommandLine.BoolVar(&pArgs.Resourcemonitor.Fragmentation, "fragmentation", pArgs.Resourcemonitor.Fragmentation, "If enable, compute the NUMA fragmentation indicators and report them as metrics and NRT attributes.")
 	CommandLine.StringVar(&pArgs.Resourcemonitor.PodSetFingerprintMethod, "pods-fingerprint-method", pArgs.Resourcemonitor.PodSetFingerprintMethod, fmt.Sprintf("Select the method to compute the pods fingerprint. Valid options: %s.", resourcemonitor.PFPMethodSupported()))
 
 	CommandLine.StringVar(&pArgs.RTE.TopologyManagerPolicy, "topology-manager-policy", pArgs.RTE.TopologyManagerPolicy, "Explicitly set the topology manager policy instead of reading from the kubelet.")
@@ -70,14 +94,26 @@ func FromFlags(pArgs *ProgArgs, args ...string) (string, string, error) {
 	CommandLine.StringVar(&pArgs.RTE.KubeletConfigFile, "kubelet-config-file", pArgs.RTE.KubeletConfigFile, "Kubelet config file path.")
 	CommandLine.StringVar(&pArgs.RTE.PodResourcesSocketPath, "podresources-socket", pArgs.RTE.PodResourcesSocketPath, "Pod Resource Socket path to use.")
 	CommandLine.BoolVar(&pArgs.RTE.PodReadinessEnable, "podreadiness", pArgs.RTE.PodReadinessEnable, "Custom condition injection using Podreadiness.")
+	CommandLine.BoolVar(&pArgs.RTE.LeaseEnable, "lease", pArgs.RTE.LeaseEnable, "Renew a per-node Lease, named after the NRT object, after each successful publish.")
+	CommandLine.StringVar(&pArgs.RTE.LeaseNamespace, "lease-namespace", pArgs.RTE.LeaseNamespace, "Namespace of the per-node Lease. Empty means the exporter pod namespace, from the REFERENCE_NAMESPACE env var.")
+	CommandLine.DurationVar(&pArgs.RTE.LeaseDuration, "lease-duration", pArgs.RTE.LeaseDuration, "Duration of the per-node Lease. Zero means three times the sleep interval.")
+	CommandLine.BoolVar(&pArgs.RTE.EventsEnable, "events", pArgs.RTE.EventsEnable, "Emit Warning events on the Node for topology anomalies and repeated publish failures.")
 	CommandLine.BoolVar(&pArgs.RTE.AddNRTOwnerEnable, "add-nrt-owner", pArgs.RTE.AddNRTOwnerEnable, "RTE will inject NRT's related node as OwnerReference to ensure cleanup if the node is deleted.")
 	CommandLine.StringVar(&pArgs.RTE.MetricsMode, "metrics-mode", pArgs.RTE.MetricsMode, fmt.Sprintf("Select the mode to expose metrics endpoint. Valid options: %s", metricssrv.ServingModeSupported()))
 	CommandLine.IntVar(&pArgs.RTE.MetricsPort, "metrics-port", pArgs.RTE.MetricsPort, "Select the port to listen for the metrics endpoint.")
 	CommandLine.StringVar(&pArgs.RTE.MetricsAddress, "metrics-ip", pArgs.RTE.MetricsAddress, "Select the IP to listen for the metrics endpoint.")
+	CommandLine.BoolVar(&pArgs.RTE.DebugEndpoint, "debug-endpoint", pArgs.RTE.DebugEndpoint, "If enable, serve the exporter state and the pprof profiles on the metrics server, under /debug/. Requires the httptlsauth metrics mode, or the client authentication enabled.")
+	CommandLine.StringVar(&pArgs.RTE.HealthAddress, "health-address", pArgs.RTE.HealthAddress, "host:port to serve the /healthz and /readyz probe endpoints on, over plain HTTP and independently from the metrics server. Empty disables them.")
+	CommandLine.DurationVar(&pArgs.RTE.HealthStaleThreshold, "health-stale-threshold", pArgs.RTE.HealthStaleThreshold, "Report not ready if a stage did not succeed within this time. 0 means 3 times the sleep interval.")
+	CommandLine.StringVar(&pArgs.RTE.MetricsLatencyBuckets, "metrics-latency-buckets", pArgs.RTE.MetricsLatencyBuckets, "Comma-separated upper bounds, in seconds, of the latency histograms buckets. Empty uses the defaults.")
+	CommandLine.StringVar(&pArgs.RTE.TracingEndpoint, "tracing-endpoint", pArgs.RTE.TracingEndpoint, "host:port of the OTLP gRPC collector to export the update cycle traces to. Empty disables the tracing.")
+	CommandLine.BoolVar(&pArgs.RTE.TracingInsecure, "tracing-insecure", pArgs.RTE.TracingInsecure, "Connect to the OTLP collector without TLS.")
 	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.CertsDir, "metrics-certs-dir", pArgs.RTE.MetricsTLSCfg.CertsDir, "certificates directory for TLS metrics serving")
 	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.CertFile, "metrics-cert-file", pArgs.RTE.MetricsTLSCfg.CertFile, "certificate file name for TLS metrics serving")
 	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.KeyFile, "metrics-key-file", pArgs.RTE.MetricsTLSCfg.KeyFile, "key file name for TLS metrics serving")
 	CommandLine.BoolVar(&pArgs.RTE.MetricsTLSCfg.WantCliAuth, "metrics-want-cli-auth", pArgs.RTE.MetricsTLSCfg.WantCliAuth, "Toggle if client certificate and authentication is required")
+	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.ClientCAFile, "metrics-client-ca-file", pArgs.RTE.MetricsTLSCfg.ClientCAFile, "PEM bundle of the CAs trusted to verify the metrics client certificates. If empty, the client certificates are verified against the system roots, hence any certificate issued by a public CA is accepted. Required with the allowed client subjects. Alternatively, you can use the env var METRICS_CLIENT_CA_FILE.")
+	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.AllowedSubjects, "metrics-allowed-client-subjects", pArgs.RTE.MetricsTLSCfg.AllowedSubjects, "Comma-separated names allowed as metrics client certificate subject common name or SAN. Requires the metrics client CA file. Empty allows any verified client.")
 	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.MinTLSVersion, "metrics-tls-min-version", pArgs.RTE.MetricsTLSCfg.MinTLSVersion, "Minimum TLS version for HTTPS metrics (e.g. VersionTLS12, VersionTLS13). Empty uses Go defaults for TLS handshake. Use the same names as kube-apiserver --tls-min-version.")
 	CommandLine.StringVar(&pArgs.RTE.MetricsTLSCfg.CipherSuites, "metrics-tls-cipher-suites", pArgs.RTE.MetricsTLSCfg.CipherSuites, "Comma-separated TLS 1.2 cipher suite names for HTTPS metrics (crypto/tls names as for kube-apiserver --tls-cipher-suites). Ignored when min version is TLS 1.3. Empty uses Go defaults.")
 
@@ -24,7 +24,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	metricssrv "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics/server"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
 )
 
@@ -41,9 +43,79 @@ func Validate(pArgs *ProgArgs) error {
 		return err
 	}
 
+	pArgs.NRTupdater.StaleCleanup, err = nrtupdater.StaleCleanupIsSupported(pArgs.NRTupdater.StaleCleanup)
+	if err != nil {
+		return err
+	}
+
+	pArgs.NRTupdater.Publisher, err = nrtupdater.PublisherIsSupported(pArgs.NRTupdater.Publisher)
+	if err != nil {
+		return err
+	}
+
+	pArgs.NRTupdater.PublisherFormat, err = nrtupdater.FileFormatIsSupported(pArgs.NRTupdater.PublisherFormat)
+	if err != nil {
+		return err
+	}
+	if pArgs.NRTupdater.PublisherHistory && pArgs.NRTupdater.PublisherFormat == nrtupdater.FileFormatYAML {
+		return fmt.Errorf("publisher history is supported only with the %s format", nrtupdater.FileFormatJSON)
+	}
+
+	pArgs.NRTupdater.WebhookPayload, err = nrtupdater.WebhookPayloadIsSupported(pArgs.NRTupdater.WebhookPayload)
+	if err != nil {
+		return err
+	}
+	if pArgs.NRTupdater.Publisher == nrtupdater.PublisherWebhook && pArgs.NRTupdater.WebhookURL == "" {
+		return fmt.Errorf("the %s publisher requires the webhook URL", nrtupdater.PublisherWebhook)
+	}
+
+	_, err = nrtupdater.ParseMirrorPatterns(pArgs.NRTupdater.NodeLabels)
+	if err != nil {
+		return fmt.Errorf("node labels: %w", err)
+	}
+	_, err = nrtupdater.ParseMirrorPatterns(pArgs.NRTupdater.NodeAnnotations)
+	if err != nil {
+		return fmt.Errorf("node annotations: %w", err)
+	}
+
+	if pArgs.RTE.DebugEndpoint && !metricsAuthenticated(pArgs.RTE.MetricsMode, pArgs.RTE.MetricsTLSCfg) {
+		// the profiles and the full state must not be readable by anyone who can reach the port
+		return fmt.Errorf("the debug endpoint requires the %s metrics mode or the client authentication enabled", metricssrv.ServingHTTPTLSAuth)
+	}
+
+	tlsCfg := pArgs.RTE.MetricsTLSCfg
+	if (tlsCfg.ClientCAFile != "" || tlsCfg.AllowedSubjects != "") && !tlsCfg.WantCliAuth {
+		return errors.New("the metrics client CA and allowed subjects require the client authentication enabled")
+	}
+	if tlsCfg.AllowedSubjects != "" && tlsCfg.ClientCAFile == "" {
+		// matching the names is pointless if any public CA can issue the certificates
+		return errors.New("the metrics allowed subjects require the metrics client CA")
+	}
+
+	_, err = metrics.ParseLatencyBuckets(pArgs.RTE.MetricsLatencyBuckets)
+	if err != nil {
+		return fmt.Errorf("metrics latency buckets: %w", err)
+	}
+
+	if pArgs.Resourcemonitor.PodAllocationMetricsMaxSeries < 0 {
+		return fmt.Errorf("invalid pod allocation metrics max series: %d", pArgs.Resourcemonitor.PodAllocationMetricsMaxSeries)
+	}
+
 	return nil
 }
 
+// metricsAuthenticated tells if the metrics server authenticates its clients.
+func metricsAuthenticated(mode string, tlsCfg metricssrv.TLSConfig) bool {
+	switch mode {
+	case metricssrv.ServingHTTPTLSAuth:
+		return true
+	case metricssrv.ServingHTTPTLS:
+		return tlsCfg.WantCliAuth
+	default:
+		return false
+	}
+}
+
 func ReadConfigletDir(configPath string) ([]fs.DirEntry, error) {
 	// to make gosec happy, the validation logic must be in the same function on which we call `os.ReadFile`.
 	// IOW, it seems the linter cannot track variable sanitization across functions.
@@ -20,6 +20,8 @@ import (
 	"errors"
 	"testing"
 
+	metricssrv "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics/server"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcetopologyexporter"
 )
@@ -73,6 +75,206 @@ func TestValidation(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name: "invalid stale cleanup mode",
+			pArgs: ProgArgs{
+				NRTupdater: nrtupdater.Args{
+					StaleCleanup: "whatever",
+				},
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "http",
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid node label pattern",
+			pArgs: ProgArgs{
+				NRTupdater: nrtupdater.Args{
+					NodeLabels: "pool/[a-",
+				},
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:       "http",
+					AddNRTOwnerEnable: true,
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "node annotations without owner references",
+			pArgs: ProgArgs{
+				NRTupdater: nrtupdater.Args{
+					NodeAnnotations: "pool.example.com/*",
+				},
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "http",
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: false,
+		},
+		{
+			name: "invalid publisher",
+			pArgs: ProgArgs{
+				NRTupdater: nrtupdater.Args{
+					Publisher: "foobar",
+				},
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "http",
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "negative pod allocation metrics max series",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "http",
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod:       "all",
+					PodAllocationMetricsMaxSeries: -1,
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid metrics latency buckets",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:           "http",
+					MetricsLatencyBuckets: "1,0.5",
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "debug endpoint without metrics",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:   "disabled",
+					DebugEndpoint: true,
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "debug endpoint over plain HTTP",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:   "http",
+					DebugEndpoint: true,
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "debug endpoint without client auth",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:   "httptls",
+					DebugEndpoint: true,
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "debug endpoint with client auth",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:   "httptls",
+					DebugEndpoint: true,
+					MetricsTLSCfg: metricssrv.TLSConfig{
+						WantCliAuth: true,
+					},
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+		},
+		{
+			name: "debug endpoint with token auth",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode:   "httptlsauth",
+					DebugEndpoint: true,
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+		},
+		{
+			name: "metrics client CA without client auth",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "httptls",
+					MetricsTLSCfg: metricssrv.TLSConfig{
+						ClientCAFile: "/etc/secrets/rte/ca/ca.crt",
+					},
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "metrics allowed subjects without client CA",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "httptls",
+					MetricsTLSCfg: metricssrv.TLSConfig{
+						WantCliAuth:     true,
+						AllowedSubjects: "prometheus",
+					},
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+			expectedError: true,
+		},
+		{
+			name: "metrics allowed subjects with client auth and CA",
+			pArgs: ProgArgs{
+				RTE: resourcetopologyexporter.Args{
+					MetricsMode: "httptls",
+					MetricsTLSCfg: metricssrv.TLSConfig{
+						WantCliAuth:     true,
+						ClientCAFile:    "/etc/secrets/rte/ca/ca.crt",
+						AllowedSubjects: "prometheus",
+					},
+				},
+				Resourcemonitor: resourcemonitor.Args{
+					PodSetFingerprintMethod: "all",
+				},
+			},
+		},
 		{
 			name: "both invalud",
 			pArgs: ProgArgs{
@@ -0,0 +1,107 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugstate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// the state items, also used as path of the endpoints serving them under PathPrefix
+const (
+	KeyScan                    = "scan"
+	KeyNRT                     = "nrt"
+	KeyPodResourcesList        = "podresources/list"
+	KeyPodResourcesAllocatable = "podresources/allocatable"
+	KeyPodFingerprint          = "podfingerprint"
+	KeyConfig                  = "config"
+)
+
+// PathPrefix is the path prefix of the endpoints serving the state items.
+const PathPrefix = "/debug/state/"
+
+// State holds the last snapshot of each state item. A nil State records nothing,
+// so the components can record unconditionally at negligible cost when debugging is disabled.
+type State struct {
+	lock  sync.RWMutex
+	items map[string][]byte
+}
+
+// New creates an empty State.
+func New() *State {
+	return &State{
+		items: make(map[string][]byte),
+	}
+}
+
+// Record stores a snapshot of the given object as the current value of the state item.
+// The object is serialized immediately, so the caller is free to mutate it afterwards.
+func (st *State) Record(key string, obj any) {
+	if st == nil {
+		return
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		klog.V(4).Infof("debugstate: cannot serialize %q: %v", key, err)
+		return
+	}
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.items[key] = data
+}
+
+// Get returns the current value of the state item, if any.
+func (st *State) Get(key string) ([]byte, bool) {
+	if st == nil {
+		return nil, false
+	}
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	data, ok := st.items[key]
+	return data, ok
+}
+
+// Handlers returns the handlers serving the state items and the pprof profiles, by path.
+func (st *State) Handlers() map[string]http.Handler {
+	handlers := map[string]http.Handler{
+		"/debug/pprof/":        http.HandlerFunc(pprof.Index),
+		"/debug/pprof/cmdline": http.HandlerFunc(pprof.Cmdline),
+		"/debug/pprof/profile": http.HandlerFunc(pprof.Profile),
+		"/debug/pprof/symbol":  http.HandlerFunc(pprof.Symbol),
+		"/debug/pprof/trace":   http.HandlerFunc(pprof.Trace),
+	}
+	for _, key := range []string{KeyScan, KeyNRT, KeyPodResourcesList, KeyPodResourcesAllocatable, KeyPodFingerprint, KeyConfig} {
+		handlers[PathPrefix+key] = st.itemHandler(key)
+	}
+	return handlers
+}
+
+func (st *State) itemHandler(key string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := st.Get(key)
+		if !ok {
+			http.Error(w, "not recorded yet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,75 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugstate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(t *testing.T, st *State, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler, ok := st.Handlers()[path]
+	if !ok {
+		t.Fatalf("missing handler for %q", path)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestRecord(t *testing.T) {
+	type sample struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	// a nil State is disabled: nothing is recorded
+	var disabled *State
+	disabled.Record(KeyScan, sample{Name: "before", Count: 1})
+	if rec := serve(t, disabled, PathPrefix+KeyScan); rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status while disabled: got %d expected %d", rec.Code, http.StatusNotFound)
+	}
+
+	st := New()
+	if rec := serve(t, st, PathPrefix+KeyScan); rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status before recording: got %d expected %d", rec.Code, http.StatusNotFound)
+	}
+	obj := sample{Name: "after", Count: 2}
+	st.Record(KeyScan, &obj)
+	// the snapshot must not be affected by later changes
+	obj.Count = 3
+
+	rec := serve(t, st, PathPrefix+KeyScan)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d expected %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Body.String(); got != `{"name":"after","count":2}` {
+		t.Errorf("unexpected body: %q", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("unexpected content type: %q", got)
+	}
+}
+
+func TestHandlersPprof(t *testing.T) {
+	rec := serve(t, New(), "/debug/pprof/")
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected pprof index status: got %d expected %d", rec.Code, http.StatusOK)
+	}
+}
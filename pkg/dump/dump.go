@@ -18,6 +18,8 @@ package dump
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"sigs.k8s.io/yaml"
 )
@@ -30,3 +32,33 @@ func Object(obj interface{}) string {
 	}
 	return string(out)
 }
+
+// ReplaceFile atomically replaces the content of file in dir with data. The data is written to
+// a temporary file in the same directory first, which is then renamed, so readers never see
+// partial content.
+func ReplaceFile(dir, file string, data []byte, perm os.FileMode) error {
+	dst, err := os.CreateTemp(dir, "__"+file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name()) // either way, we need to get rid of this
+
+	_, err = dst.Write(data)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	err = dst.Chmod(perm)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), filepath.Join(dir, file))
+}
@@ -1,6 +1,8 @@
 package dump
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
@@ -35,3 +37,38 @@ func TestDumpObject(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceFile(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, content := range []string{"first", "second"} {
+		err := ReplaceFile(dir, "data.txt", []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("ReplaceFile failed: %v", err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(dir, "data.txt"))
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if string(got) != content {
+			t.Errorf("got %q expected %q", string(got), content)
+		}
+	}
+
+	st, err := os.Stat(filepath.Join(dir, "data.txt"))
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if st.Mode().Perm() != 0644 {
+		t.Errorf("got mode %v expected %v", st.Mode().Perm(), os.FileMode(0644))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("leftover temporary files: %v", entries)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// the stages of the exporter pipeline
+const (
+	StagePodResources = "podresources"
+	StageScan         = "scan"
+	StagePublish      = "publish"
+	StageInformers    = "informers"
+)
+
+// DefaultStaleThreshold is the maximum age of the last success of a stage, if not configured otherwise.
+const DefaultStaleThreshold = 3 * time.Minute
+
+// StageReport is the health of a stage
+type StageReport struct {
+	Name        string     `json:"name"`
+	Healthy     bool       `json:"healthy"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Age         string     `json:"age,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Report is the health of the pipeline. It is ready if all the stages are healthy.
+type Report struct {
+	Ready  bool          `json:"ready"`
+	Stages []StageReport `json:"stages"`
+}
+
+type stageStatus struct {
+	lastSuccess time.Time
+	lastError   string
+}
+
+// Tracker records the outcome of the pipeline stages. A tracked stage is healthy if it succeeded
+// at least once, no longer than the stale threshold ago. A checked stage is healthy if its check
+// function returns no error.
+type Tracker struct {
+	lock           sync.RWMutex
+	staleThreshold time.Duration
+	stages         map[string]*stageStatus
+	checks         map[string]func() error
+	now            func() time.Time
+}
+
+// NewPipelineTracker creates a Tracker expecting the stages of the exporter pipeline to report their outcome.
+func NewPipelineTracker() *Tracker {
+	return NewTracker(StagePodResources, StageScan, StagePublish)
+}
+
+// NewTracker creates a Tracker expecting the given stages to report their outcome.
+func NewTracker(stages ...string) *Tracker {
+	tr := &Tracker{
+		staleThreshold: DefaultStaleThreshold,
+		stages:         make(map[string]*stageStatus),
+		checks:         make(map[string]func() error),
+		now:            time.Now,
+	}
+	for _, stage := range stages {
+		tr.stages[stage] = &stageStatus{}
+	}
+	return tr
+}
+
+// SetStaleThreshold sets the maximum age of the last success of the tracked stages.
+// Non-positive values select DefaultStaleThreshold.
+func (tr *Tracker) SetStaleThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		threshold = DefaultStaleThreshold
+	}
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	tr.staleThreshold = threshold
+}
+
+// MarkSuccess records the success of the stage. A nil Tracker discards it.
+func (tr *Tracker) MarkSuccess(stage string) {
+	if tr == nil {
+		return
+	}
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	st := tr.stageLocked(stage)
+	st.lastSuccess = tr.now()
+	st.lastError = ""
+}
+
+// MarkFailure records the failure of the stage. A nil Tracker discards it.
+func (tr *Tracker) MarkFailure(stage string, err error) {
+	if tr == nil {
+		return
+	}
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	st := tr.stageLocked(stage)
+	st.lastError = err.Error()
+}
+
+// AddCheck makes the health of the stage depend on the given function, evaluated on each report.
+func (tr *Tracker) AddCheck(stage string, check func() error) {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	tr.checks[stage] = check
+}
+
+func (tr *Tracker) stageLocked(stage string) *stageStatus {
+	st, ok := tr.stages[stage]
+	if !ok {
+		st = &stageStatus{}
+		tr.stages[stage] = st
+	}
+	return st
+}
+
+// Report computes the current health of the pipeline. The stages are sorted by name.
+func (tr *Tracker) Report() Report {
+	tr.lock.RLock()
+	defer tr.lock.RUnlock()
+
+	now := tr.now()
+	rep := Report{
+		Ready:  true,
+		Stages: make([]StageReport, 0, len(tr.stages)+len(tr.checks)),
+	}
+	for name, st := range tr.stages {
+		sr := StageReport{
+			Name:  name,
+			Error: st.lastError,
+		}
+		if !st.lastSuccess.IsZero() {
+			ts := st.lastSuccess
+			age := now.Sub(ts)
+			sr.LastSuccess = &ts
+			sr.Age = age.Round(time.Millisecond).String()
+			sr.Healthy = age <= tr.staleThreshold
+		}
+		rep.Stages = append(rep.Stages, sr)
+	}
+	for name, check := range tr.checks {
+		sr := StageReport{
+			Name:    name,
+			Healthy: true,
+		}
+		if err := check(); err != nil {
+			sr.Healthy = false
+			sr.Error = err.Error()
+		}
+		rep.Stages = append(rep.Stages, sr)
+	}
+	for _, sr := range rep.Stages {
+		rep.Ready = rep.Ready && sr.Healthy
+	}
+	sort.Slice(rep.Stages, func(i, j int) bool {
+		return rep.Stages[i].Name < rep.Stages[j].Name
+	})
+	return rep
+}
+
+// LivenessHandler serves the health report. It always succeeds, because a stale
+// pipeline is not fixed by restarting the exporter; use the readiness for that.
+func (tr *Tracker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, tr.Report())
+	})
+}
+
+// ReadinessHandler serves the health report, failing if any stage is not healthy.
+func (tr *Tracker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := tr.Report()
+		code := http.StatusOK
+		if !rep.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeReport(w, code, rep)
+	})
+}
+
+func writeReport(w http.ResponseWriter, code int, rep Report) {
+	data, err := json.Marshal(rep)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// Serve exposes /healthz and /readyz on a plain HTTP listener bound to addr, separate from the metrics server,
+// so the kubelet probes work regardless of the metrics serving mode and of its client authentication.
+// The listener is bound before returning, and closed once ctx is done.
+func Serve(ctx context.Context, addr string, tr *Tracker) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", tr.LivenessHandler())
+	mux.Handle("/readyz", tr.ReadinessHandler())
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		err := srv.Serve(lis)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "error serving the health endpoints", "address", addr)
+		}
+	}()
+	klog.Infof("health endpoints served on %s", addr)
+	return nil
+}
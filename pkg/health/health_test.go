@@ -0,0 +1,184 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := NewTracker(StageScan, StagePublish)
+	tr.now = func() time.Time { return ts }
+	tr.SetStaleThreshold(time.Minute)
+
+	rep := tr.Report()
+	if rep.Ready {
+		t.Fatalf("ready before any stage succeeded: %+v", rep)
+	}
+
+	tr.MarkSuccess(StageScan)
+	tr.MarkSuccess(StagePublish)
+	if rep = tr.Report(); !rep.Ready {
+		t.Fatalf("not ready after all stages succeeded: %+v", rep)
+	}
+
+	// a failure is tolerated until the last success becomes stale
+	ts = ts.Add(30 * time.Second)
+	tr.MarkFailure(StagePublish, errors.New("fake publish failure"))
+	if rep = tr.Report(); !rep.Ready {
+		t.Fatalf("not ready within the stale threshold: %+v", rep)
+	}
+	ts = ts.Add(time.Minute)
+	tr.MarkSuccess(StageScan)
+	rep = tr.Report()
+	if rep.Ready {
+		t.Fatalf("ready with a stale stage: %+v", rep)
+	}
+	expected := map[string]StageReport{
+		StagePublish: {Name: StagePublish, Healthy: false, Age: "1m30s", Error: "fake publish failure"},
+		StageScan:    {Name: StageScan, Healthy: true, Age: "0s"},
+	}
+	for _, sr := range rep.Stages {
+		exp := expected[sr.Name]
+		if sr.Healthy != exp.Healthy || sr.Age != exp.Age || sr.Error != exp.Error {
+			t.Errorf("unexpected stage report: got %+v expected %+v", sr, exp)
+		}
+	}
+}
+
+func TestChecks(t *testing.T) {
+	tr := NewTracker()
+	var checkErr error
+	tr.AddCheck(StageInformers, func() error { return checkErr })
+	if rep := tr.Report(); !rep.Ready {
+		t.Fatalf("not ready with passing check: %+v", rep)
+	}
+	checkErr = errors.New("cache not synced")
+	rep := tr.Report()
+	if rep.Ready || len(rep.Stages) != 1 || rep.Stages[0].Error != "cache not synced" {
+		t.Fatalf("unexpected report with failing check: %+v", rep)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	tr := NewTracker(StageScan)
+
+	type testCase struct {
+		name         string
+		handler      http.Handler
+		markScan     bool
+		expectedCode int
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:         "liveness, not ready",
+			handler:      tr.LivenessHandler(),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "readiness, not ready",
+			handler:      tr.ReadinessHandler(),
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "readiness, ready",
+			handler:      tr.ReadinessHandler(),
+			markScan:     true,
+			expectedCode: http.StatusOK,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			if tcase.markScan {
+				tr.MarkSuccess(StageScan)
+			}
+			rec := httptest.NewRecorder()
+			tcase.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tcase.expectedCode {
+				t.Errorf("unexpected status: got %d expected %d", rec.Code, tcase.expectedCode)
+			}
+			var rep Report
+			err := json.Unmarshal(rec.Body.Bytes(), &rep)
+			if err != nil {
+				t.Fatalf("malformed report: %v", err)
+			}
+			if len(rep.Stages) != 1 || rep.Stages[0].Name != StageScan {
+				t.Errorf("unexpected stages: %+v", rep.Stages)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := NewTracker(StageScan)
+	err = Serve(ctx, addr, tr)
+	if err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+
+	get := func(path string) (int, error) {
+		resp, err := http.Get(fmt.Sprintf("http://%s%s", addr, path))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	for path, expected := range map[string]int{
+		"/healthz": http.StatusOK,
+		"/readyz":  http.StatusServiceUnavailable,
+	} {
+		code, err := get(path)
+		if err != nil {
+			t.Fatalf("failed to get %q: %v", path, err)
+		}
+		if code != expected {
+			t.Errorf("unexpected status for %q: got %d expected %d", path, code, expected)
+		}
+	}
+	tr.MarkSuccess(StageScan)
+	if code, err := get("/readyz"); err != nil || code != http.StatusOK {
+		t.Errorf("not ready after success: %d %v", code, err)
+	}
+
+	cancel()
+	for range 50 {
+		if _, err = get("/healthz"); err != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("still serving after the context is done")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+// renewDivisor sets the minimum interval between renewals as a fraction of the Lease duration:
+// the Lease stays valid between renewals, and a burst of publishes does not flood the apiserver.
+const renewDivisor = 4
+
+// Identity describes the exporter holding the Lease.
+type Identity struct {
+	// Holder is the name of the node the exporter runs on
+	Holder string
+	// Labels are set on the Lease, so it can be found like the other objects written by the exporter
+	Labels map[string]string
+}
+
+// Lease renews a per-node coordination.k8s.io Lease, named after the NRT object, so the
+// consumers can tell a quiet node, whose NRT does not change, from a dead exporter.
+// If the Lease is not renewed within its duration, the NRT data should be considered stale.
+type Lease struct {
+	cli       kubernetes.Interface
+	namespace string
+	name      string
+	ident     Identity
+	duration  time.Duration
+	now       func() time.Time
+	lastRenew time.Time
+}
+
+func NewLease(cli kubernetes.Interface, namespace, name string, ident Identity, duration time.Duration) (*Lease, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("missing lease namespace")
+	}
+	if duration < time.Second {
+		return nil, fmt.Errorf("lease duration too short: %v", duration)
+	}
+	return &Lease{
+		cli:       cli,
+		namespace: namespace,
+		name:      name,
+		ident:     ident,
+		duration:  duration,
+		now:       time.Now,
+	}, nil
+}
+
+// Beat renews the Lease, creating it if needed. Does nothing if the Lease was renewed
+// less than a fraction of its duration ago.
+func (hb *Lease) Beat(ctx context.Context) error {
+	ts := hb.now()
+	if !hb.lastRenew.IsZero() && ts.Sub(hb.lastRenew) < hb.duration/renewDivisor {
+		return nil
+	}
+	err := hb.renew(ctx, metav1.NewMicroTime(ts))
+	if err != nil {
+		return err
+	}
+	hb.lastRenew = ts
+	return nil
+}
+
+func (hb *Lease) renew(ctx context.Context, now metav1.MicroTime) error {
+	leases := hb.cli.CoordinationV1().Leases(hb.namespace)
+
+	lease, err := leases.Get(ctx, hb.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		leaseNew := coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      hb.name,
+				Namespace: hb.namespace,
+				Labels:    hb.ident.Labels,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(hb.ident.Holder),
+				LeaseDurationSeconds: ptr.To(int32(hb.duration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, &leaseNew, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	leaseMutated := lease.DeepCopy()
+	if leaseMutated.Spec.HolderIdentity == nil || *leaseMutated.Spec.HolderIdentity != hb.ident.Holder {
+		leaseMutated.Spec.HolderIdentity = ptr.To(hb.ident.Holder)
+		leaseMutated.Spec.AcquireTime = &now
+	}
+	leaseMutated.Spec.LeaseDurationSeconds = ptr.To(int32(hb.duration.Seconds()))
+	leaseMutated.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, leaseMutated, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,151 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package heartbeat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewLease(t *testing.T) {
+	type testCase struct {
+		name          string
+		namespace     string
+		duration      time.Duration
+		expectedError bool
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:      "valid",
+			namespace: "rte",
+			duration:  time.Minute,
+		},
+		{
+			name:          "missing namespace",
+			duration:      time.Minute,
+			expectedError: true,
+		},
+		{
+			name:          "too short",
+			namespace:     "rte",
+			duration:      time.Millisecond,
+			expectedError: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := NewLease(fake.NewSimpleClientset(), tcase.namespace, "node-a", Identity{Holder: "node-a"}, tcase.duration)
+			gotErr := (err != nil)
+			if gotErr != tcase.expectedError {
+				t.Errorf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+		})
+	}
+}
+
+func TestBeat(t *testing.T) {
+	ctx := context.Background()
+	cli := fake.NewSimpleClientset()
+	hb, err := NewLease(cli, "rte", "node-a", Identity{Holder: "node-a", Labels: map[string]string{"example.com/node": "node-a"}}, 3*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create the lease heartbeat: %v", err)
+	}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hb.now = func() time.Time { return ts }
+
+	err = hb.Beat(ctx)
+	if err != nil {
+		t.Fatalf("first beat failed: %v", err)
+	}
+	lease, err := cli.CoordinationV1().Leases("rte").Get(ctx, "node-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease not created: %v", err)
+	}
+	if *lease.Spec.HolderIdentity != "node-a" || *lease.Spec.LeaseDurationSeconds != 180 {
+		t.Errorf("unexpected lease spec: %+v", lease.Spec)
+	}
+	if lease.Labels["example.com/node"] != "node-a" {
+		t.Errorf("unexpected lease labels: %v", lease.Labels)
+	}
+	if !lease.Spec.RenewTime.Time.Equal(ts) || !lease.Spec.AcquireTime.Time.Equal(ts) {
+		t.Errorf("unexpected lease times: %+v", lease.Spec)
+	}
+
+	ts2 := ts.Add(time.Minute)
+	hb.now = func() time.Time { return ts2 }
+	err = hb.Beat(ctx)
+	if err != nil {
+		t.Fatalf("second beat failed: %v", err)
+	}
+	lease, err = cli.CoordinationV1().Leases("rte").Get(ctx, "node-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease disappeared: %v", err)
+	}
+	if !lease.Spec.RenewTime.Time.Equal(ts2) {
+		t.Errorf("lease not renewed: %v", lease.Spec.RenewTime)
+	}
+	if !lease.Spec.AcquireTime.Time.Equal(ts) {
+		t.Errorf("acquire time changed on renewal: %v", lease.Spec.AcquireTime)
+	}
+}
+
+func TestBeatThrottled(t *testing.T) {
+	ctx := context.Background()
+	cli := fake.NewSimpleClientset()
+	hb, err := NewLease(cli, "rte", "node-a", Identity{Holder: "node-a"}, 4*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create the lease heartbeat: %v", err)
+	}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hb.now = func() time.Time { return ts }
+
+	err = hb.Beat(ctx)
+	if err != nil {
+		t.Fatalf("first beat failed: %v", err)
+	}
+	actions := len(cli.Actions())
+
+	// the minimum renewal interval is a quarter of the duration
+	for _, elapsed := range []time.Duration{time.Second, 30 * time.Second, 59 * time.Second} {
+		hb.now = func() time.Time { return ts.Add(elapsed) }
+		err = hb.Beat(ctx)
+		if err != nil {
+			t.Fatalf("beat after %v failed: %v", elapsed, err)
+		}
+	}
+	if got := len(cli.Actions()); got != actions {
+		t.Errorf("lease renewed within the minimum interval: %d actions", got-actions)
+	}
+
+	ts2 := ts.Add(time.Minute)
+	hb.now = func() time.Time { return ts2 }
+	err = hb.Beat(ctx)
+	if err != nil {
+		t.Fatalf("beat failed: %v", err)
+	}
+	lease, err := cli.CoordinationV1().Leases("rte").Get(ctx, "node-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease disappeared: %v", err)
+	}
+	if !lease.Spec.RenewTime.Time.Equal(ts2) {
+		t.Errorf("lease not renewed after the minimum interval: %v", lease.Spec.RenewTime)
+	}
+}
@@ -20,6 +20,8 @@ const (
 	RTEUpdate      = "k8stopoawareschedwg/rte-update"
 	SleepDuration  = "k8stopoawareschedwg/sleep-duration"
 	UpdateInterval = "k8stopoawareschedwg/update-interval"
+	ScanSequence   = "k8stopoawareschedwg/scan-sequence"
+	ScanTimestamp  = "k8stopoawareschedwg/scan-timestamp"
 )
 
 func Merge(kvs ...map[string]string) map[string]string {
@@ -0,0 +1,127 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sevents
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/version"
+)
+
+const (
+	ReasonZeroCapacity              = "TopologyZeroCapacity"
+	ReasonAllocatedMoreThanCapacity = "TopologyAllocatedMoreThanCapacity"
+	ReasonNegativeAvailable         = "TopologyNegativeAvailable"
+	ReasonPublishFailed             = "TopologyPublishFailed"
+)
+
+const (
+	// DedupWindow is the minimum interval between two identical events.
+	DedupWindow = 10 * time.Minute
+	// the overall rate is bounded to avoid flooding the apiserver if many anomalies show up at once
+	eventsQPS   = 0.1
+	eventsBurst = 5
+)
+
+// Reporter emits Warning events about topology anomalies.
+type Reporter interface {
+	Warningf(reason, messageFmt string, args ...interface{})
+}
+
+type DisabledReporter struct{}
+
+func (dr DisabledReporter) Warningf(reason, messageFmt string, args ...interface{}) {}
+
+// NodeReporter emits the events on the Node object. Identical events (same reason and message)
+// are emitted at most once per DedupWindow, and the overall event rate is limited.
+type NodeReporter struct {
+	recorder    record.EventRecorder
+	broadcaster record.EventBroadcaster
+	ref         *v1.ObjectReference
+	limiter     flowcontrol.RateLimiter
+	lock        sync.Mutex
+	lastSeen    map[string]time.Time
+	now         func() time.Time
+}
+
+func NewNodeReporter(cli kubernetes.Interface, nodeName string) *NodeReporter {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cli.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: version.ProgramName, Host: nodeName})
+	nr := newNodeReporter(recorder, nodeName)
+	nr.broadcaster = broadcaster
+	return nr
+}
+
+func newNodeReporter(recorder record.EventRecorder, nodeName string) *NodeReporter {
+	return &NodeReporter{
+		recorder: recorder,
+		ref: &v1.ObjectReference{
+			Kind: "Node",
+			Name: nodeName,
+			// like the kubelet does, so the events are associated to the node regardless of its UID
+			UID: types.UID(nodeName),
+		},
+		limiter:  flowcontrol.NewTokenBucketRateLimiter(eventsQPS, eventsBurst),
+		lastSeen: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (nr *NodeReporter) Warningf(reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	key := reason + "/" + message
+
+	nr.lock.Lock()
+	defer nr.lock.Unlock()
+	now := nr.now()
+	for k, ts := range nr.lastSeen {
+		if now.Sub(ts) >= DedupWindow {
+			delete(nr.lastSeen, k)
+		}
+	}
+	if _, ok := nr.lastSeen[key]; ok {
+		klog.V(6).Infof("events: suppressed duplicate %s: %s", reason, message)
+		return
+	}
+	if !nr.limiter.TryAccept() {
+		// not marked as seen, so the next occurrence gets another chance
+		klog.V(4).Infof("events: rate limited %s: %s", reason, message)
+		return
+	}
+	nr.lastSeen[key] = now
+	nr.recorder.Event(nr.ref, v1.EventTypeWarning, reason, message)
+}
+
+// Shutdown flushes and stops the event broadcaster, if any.
+func (nr *NodeReporter) Shutdown() {
+	if nr.broadcaster == nil {
+		return
+	}
+	nr.broadcaster.Shutdown()
+}
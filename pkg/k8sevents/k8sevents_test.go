@@ -0,0 +1,99 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sevents
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func drainEvents(rec *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case ev := <-rec.Events:
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
+
+func TestWarningfDeduplication(t *testing.T) {
+	rec := record.NewFakeRecorder(100)
+	nr := newNodeReporter(rec, "node-a")
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nr.now = func() time.Time { return ts }
+
+	nr.Warningf(ReasonZeroCapacity, "zero capacity for %q", "cpu")
+	nr.Warningf(ReasonZeroCapacity, "zero capacity for %q", "cpu")
+	nr.Warningf(ReasonZeroCapacity, "zero capacity for %q", "memory")
+	events := drainEvents(rec)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %v", len(events), events)
+	}
+	for _, ev := range events {
+		if !strings.HasPrefix(ev, "Warning "+ReasonZeroCapacity) {
+			t.Errorf("unexpected event: %q", ev)
+		}
+	}
+
+	ts = ts.Add(DedupWindow / 2)
+	nr.Warningf(ReasonZeroCapacity, "zero capacity for %q", "cpu")
+	if events = drainEvents(rec); len(events) != 0 {
+		t.Fatalf("duplicate emitted within the window: %v", events)
+	}
+
+	ts = ts.Add(DedupWindow)
+	nr.Warningf(ReasonZeroCapacity, "zero capacity for %q", "cpu")
+	if events = drainEvents(rec); len(events) != 1 {
+		t.Fatalf("expected the event again after the window, got %v", events)
+	}
+}
+
+func TestWarningfRateLimit(t *testing.T) {
+	rec := record.NewFakeRecorder(100)
+	nr := newNodeReporter(rec, "node-a")
+
+	for idx := 0; idx < 2*eventsBurst; idx++ {
+		nr.Warningf(ReasonNegativeAvailable, "negative available size on zone %d", idx)
+	}
+	events := drainEvents(rec)
+	if len(events) != eventsBurst {
+		t.Fatalf("expected %d events, got %d: %v", eventsBurst, len(events), events)
+	}
+
+	// rate limited events are not marked as seen, so they are not deduplicated later
+	nr.limiter.Stop()
+	nr.limiter = alwaysAccept{}
+	nr.Warningf(ReasonNegativeAvailable, "negative available size on zone %d", eventsBurst)
+	if events = drainEvents(rec); len(events) != 1 {
+		t.Errorf("expected the previously rate limited event, got %v", events)
+	}
+}
+
+type alwaysAccept struct{}
+
+func (aa alwaysAccept) TryAccept() bool                { return true }
+func (aa alwaysAccept) Accept()                        {}
+func (aa alwaysAccept) Stop()                          {}
+func (aa alwaysAccept) QPS() float32                   { return 0 }
+func (aa alwaysAccept) Wait(ctx context.Context) error { return nil }
@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8shelpers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodeNameFromEnv returns the name of the node this process runs on, exposed through the downward API
+// in the NODE_NAME env var, or fallback if unset. This is the name to scope the informers with:
+// the NRT object name (--hostname) can differ from it.
+func NodeNameFromEnv(fallback string) string {
+	if val, ok := os.LookupEnv("NODE_NAME"); ok && val != "" {
+		return val
+	}
+	return fallback
+}
+
+// NewNodeScopedInformerFactory returns a SharedInformerFactory whose Node and Pod informers
+// only watch the given node and the pods scheduled on it, to keep the apiserver load
+// independent from the cluster size. Informers for other kinds are not restricted.
+func NewNodeScopedInformerFactory(cli kubernetes.Interface, nodeName string, resync time.Duration) informers.SharedInformerFactory {
+	factory := informers.NewSharedInformerFactory(cli, resync)
+	// InformerFor registers the first constructor for each kind, so every later consumer gets the filtered informers
+	factory.InformerFor(&corev1.Node{}, func(c kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return coreinformers.NewFilteredNodeInformer(c, resync, cache.Indexers{}, func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		})
+	})
+	factory.InformerFor(&corev1.Pod{}, func(c kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return coreinformers.NewFilteredPodInformer(c, metav1.NamespaceAll, resync, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		})
+	})
+	return factory
+}
+
+// NewSingleNodeInformerFactory returns a SharedInformerFactory meant only for the Node informer, which watches
+// just the given node. Unlike NewNodeScopedInformerFactory, it starts no other informer.
+func NewSingleNodeInformerFactory(cli kubernetes.Interface, nodeName string, resync time.Duration) informers.SharedInformerFactory {
+	return informers.NewSharedInformerFactoryWithOptions(cli, resync, informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+	}))
+}
+
+// InformersSynced reports, without blocking, if the caches of all the informers started so far are synced.
+func InformersSynced(factory informers.SharedInformerFactory) error {
+	stopped := make(chan struct{})
+	close(stopped)
+	for v, ok := range factory.WaitForCacheSync(stopped) {
+		if !ok {
+			return fmt.Errorf("cache not synced: %v", v)
+		}
+	}
+	return nil
+}
+
+// StartAndSync starts all the informers requested so far and waits for their caches to sync.
+// Can be called multiple times, as consumers register their informers.
+func StartAndSync(ctx context.Context, factory informers.SharedInformerFactory) error {
+	factory.Start(ctx.Done())
+	synced := factory.WaitForCacheSync(ctx.Done())
+	for v, ok := range synced {
+		if !ok {
+			return fmt.Errorf("caches failed to sync: %v", v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8shelpers
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNodeScopedInformerFactory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cli := fake.NewSimpleClientset()
+	factory := NewNodeScopedInformerFactory(cli, "test-node", 0)
+	factory.Core().V1().Nodes().Informer()
+	factory.Core().V1().Pods().Informer()
+
+	err := StartAndSync(ctx, factory)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	expected := map[string]string{
+		"nodes": "metadata.name=test-node",
+		"pods":  "spec.nodeName=test-node",
+	}
+	got := make(map[string]string)
+	for _, action := range cli.Actions() {
+		listAction, ok := action.(k8stesting.ListAction)
+		if !ok {
+			continue
+		}
+		got[action.GetResource().Resource] = listAction.GetListRestrictions().Fields.String()
+	}
+	for resource, selector := range expected {
+		if got[resource] != selector {
+			t.Errorf("unexpected field selector for %q: got %q expected %q", resource, got[resource], selector)
+		}
+	}
+}
+
+func TestNodeNameFromEnv(t *testing.T) {
+	t.Setenv("NODE_NAME", "real-node")
+	if got := NodeNameFromEnv("nrt-name"); got != "real-node" {
+		t.Errorf("unexpected node name: got %q expected %q", got, "real-node")
+	}
+	t.Setenv("NODE_NAME", "")
+	if got := NodeNameFromEnv("nrt-name"); got != "nrt-name" {
+		t.Errorf("unexpected node name: got %q expected %q", got, "nrt-name")
+	}
+}
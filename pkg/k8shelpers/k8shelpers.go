@@ -1,6 +1,8 @@
 package k8shelpers
 
 import (
+	"os"
+
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -8,16 +10,17 @@ import (
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
 )
 
-func GetTopologyClient(kubeConfig string) (*topologyclientset.Clientset, error) {
-	// Set up an in-cluster K8S client.
-	var config *restclient.Config
-	var err error
-
+// GetRestConfig returns the in-cluster configuration, or the one from the given kubeconfig file if not empty.
+func GetRestConfig(kubeConfig string) (*restclient.Config, error) {
 	if kubeConfig == "" {
-		config, err = restclient.InClusterConfig()
-	} else {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
+		return restclient.InClusterConfig()
 	}
+	return clientcmd.BuildConfigFromFlags("", kubeConfig)
+}
+
+func GetTopologyClient(kubeConfig string) (*topologyclientset.Clientset, error) {
+	// Set up an in-cluster K8S client.
+	config, err := GetRestConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -31,17 +34,19 @@ func GetTopologyClient(kubeConfig string) (*topologyclientset.Clientset, error)
 
 func GetK8sClient(kubeConfig string) (*kubernetes.Clientset, error) {
 	// Set up an in-cluster K8S client.
-	var config *restclient.Config
-	var err error
-
-	if kubeConfig == "" {
-		config, err = restclient.InClusterConfig()
-	} else {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
-	}
+	config, err := GetRestConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	return kubernetes.NewForConfig(config)
 }
+
+// NamespaceOrDefault returns namespace if not empty, or else the namespace from the REFERENCE_NAMESPACE env var,
+// which in the reference deployment is the exporter pod namespace. Returns empty if both are unset.
+func NamespaceOrDefault(namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return os.Getenv("REFERENCE_NAMESPACE")
+}
@@ -0,0 +1,35 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8shelpers
+
+import (
+	"testing"
+)
+
+func TestNamespaceOrDefault(t *testing.T) {
+	t.Setenv("REFERENCE_NAMESPACE", "rte")
+	if got := NamespaceOrDefault("custom"); got != "custom" {
+		t.Errorf("unexpected namespace: got %q expected %q", got, "custom")
+	}
+	if got := NamespaceOrDefault(""); got != "rte" {
+		t.Errorf("unexpected namespace: got %q expected %q", got, "rte")
+	}
+	t.Setenv("REFERENCE_NAMESPACE", "")
+	if got := NamespaceOrDefault(""); got != "" {
+		t.Errorf("unexpected namespace: got %q expected empty", got)
+	}
+}
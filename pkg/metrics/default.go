@@ -0,0 +1,141 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+var (
+	defaultOnce    sync.Once
+	defaultMetrics *Metrics
+)
+
+// Default returns the process-wide instance, whose node name is set by Setup. It is created and
+// registered in the controller-runtime registry, served by the metrics server, on first use.
+// The package-level functions refer to it. The programs registering their own instance in
+// the controller-runtime registry must not use it.
+func Default() *Metrics {
+	defaultOnce.Do(func() {
+		m, err := New("", ctrlmetrics.Registry)
+		if err != nil {
+			klog.Warningf("metrics: cannot register the default instance, its data won't be served: %v", err)
+			m, _ = New("", nil) // can't fail without a registry
+		}
+		defaultMetrics = m
+	})
+	return defaultMetrics
+}
+
+func UpdateNodeResourceTopologyWritesMetric(operation, trigger string) {
+	Default().UpdateNodeResourceTopologyWritesMetric(operation, trigger)
+}
+
+func UpdatePodResourceApiCallsFailuresMetric(funcName string) {
+	Default().UpdatePodResourceApiCallsFailuresMetric(funcName)
+}
+
+func UpdateOperationDelayMetric(opName, trigger string, operationDelay float64) {
+	Default().UpdateOperationDelayMetric(opName, trigger, operationDelay)
+}
+
+func UpdateWakeupDelayMetric(trigger string, wakeupDelay float64) {
+	Default().UpdateWakeupDelayMetric(trigger, wakeupDelay)
+}
+
+func UpdateNodeResourceTopologyPatchFailuresMetric(trigger string) {
+	Default().UpdateNodeResourceTopologyPatchFailuresMetric(trigger)
+}
+
+func UpdateNodeResourceTopologyPatchSizeRatioMetric(ratio float64) {
+	Default().UpdateNodeResourceTopologyPatchSizeRatioMetric(ratio)
+}
+
+func UpdateScanSequenceMetric(seq uint64) {
+	Default().UpdateScanSequenceMetric(seq)
+}
+
+func UpdateScanTimestampMetric(ts time.Time) {
+	Default().UpdateScanTimestampMetric(ts)
+}
+
+func UpdatePublishedScanSequenceMetric(seq uint64) {
+	Default().UpdatePublishedScanSequenceMetric(seq)
+}
+
+func UpdateZoneResourcesMetric(zones v1alpha2.ZoneList) {
+	Default().UpdateZoneResourcesMetric(zones)
+}
+
+func UpdateFragmentationMetric(frags []ResourceFragmentation) {
+	Default().UpdateFragmentationMetric(frags)
+}
+
+func UpdatePodAllocationsMetric(allocs []PodAllocation, maxSeries int) {
+	Default().UpdatePodAllocationsMetric(allocs, maxSeries)
+}
+
+func ObservePodResourcesAPICallDuration(funcName string, elapsed time.Duration) {
+	Default().ObservePodResourcesAPICallDuration(funcName, elapsed)
+}
+
+func ObserveScanDuration(trigger string, elapsed time.Duration) {
+	Default().ObserveScanDuration(trigger, elapsed)
+}
+
+func ObservePublishDuration(operation, trigger string, elapsed time.Duration) {
+	Default().ObservePublishDuration(operation, trigger, elapsed)
+}
+
+func ObserveWakeupJitter(jitter time.Duration) {
+	Default().ObserveWakeupJitter(jitter)
+}
+
+// Setup sets the node name of the default instance: the given one if not empty,
+// otherwise from the NODE_NAME env var, falling back to the hostname.
+func Setup(nname string) error {
+	val, err := ResolveNodeName(nname)
+	if err != nil {
+		return err
+	}
+	Default().setNodeName(val)
+	return nil
+}
+
+// ResolveNodeName returns the given node name if not empty, otherwise the one
+// from the NODE_NAME env var, falling back to the hostname.
+func ResolveNodeName(nname string) (string, error) {
+	if nname != "" {
+		return nname, nil
+	}
+	if val, ok := os.LookupEnv("NODE_NAME"); ok {
+		return val, nil
+	}
+	return os.Hostname()
+}
+
+// GetNodeName is meant for testing purposes
+func GetNodeName() string {
+	return defaultMetrics.NodeName()
+}
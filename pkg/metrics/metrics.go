@@ -17,109 +17,545 @@ limitations under the License.
 package metrics
 
 import (
-	"os"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 )
 
-var nodeName string
+// DefaultLatencyBuckets are the default buckets of the latency histograms, in seconds
+var DefaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
 
-var (
-	PodResourceApiCallsFailure = promauto.With(ctrlmetrics.Registry).NewCounterVec(prometheus.CounterOpts{
-		Name: "rte_podresource_api_call_failures_total",
-		Help: "The total number of podresource api calls that failed by the updater",
-	}, []string{"node", "function_name"})
+// DefaultPodAllocationMaxSeries is the default cardinality limit of the pod resource allocation gauge
+const DefaultPodAllocationMaxSeries = 1000
 
-	NodeResourceTopologyWrites = promauto.With(ctrlmetrics.Registry).NewCounterVec(prometheus.CounterOpts{
-		Name: "rte_noderesourcetopology_writes_total",
-		Help: "The total number of NodeResourceTopology writes",
-	}, []string{"node", "operation", "trigger"})
+// PodAllocation is the amount of a resource exclusively allocated to a container in a topology zone
+type PodAllocation struct {
+	Namespace string
+	Pod       string
+	Container string
+	Zone      string
+	Resource  string
+	Amount    int64
+}
 
-	OperationDelay = promauto.With(ctrlmetrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
-		Name: "rte_operation_delay_milliseconds",
-		Help: "The latency between exporting stages, milliseconds",
-	}, []string{"node", "operation_name", "trigger"})
+// ResourceFragmentation describes how the available amount of a resource is spread across the zones
+type ResourceFragmentation struct {
+	Resource      string
+	LargestFree   int64
+	StrandedRatio float64
+}
 
-	WakeupDelay = promauto.With(ctrlmetrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
-		Name: "rte_wakeup_delay_milliseconds",
-		Help: "The wakeup delay of the monitor code, milliseconds",
-	}, []string{"node", "trigger"})
+// zoneResourceKey identifies a series of the zone resource gauges
+type zoneResourceKey struct {
+	zone     string
+	resource string
+}
 
-	NodeResourceTopologyPatchFailure = promauto.With(ctrlmetrics.Registry).NewCounterVec(prometheus.CounterOpts{
-		Name: "rte_noderesourcetopology_patch_failures_total",
-		Help: "The total number of times the NodeResourceTopology patching failed",
-	}, []string{"node", "trigger"})
+// podAllocationKey identifies a series of the pod resource allocation gauge
+type podAllocationKey struct {
+	namespace string
+	pod       string
+	container string
+	zone      string
+	resource  string
+}
 
-	NodeResourceTopologyPatchSizeRatio = promauto.With(ctrlmetrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "rte_noderesourcetopology_patch_size_ratio",
-		Help:    "The ratio of patch size to full object size (0.0 to 1.0)",
-		Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0},
-	}, []string{"node"})
-)
+// Metrics holds the collectors of an exporter instance, whose series are all labeled with its node name.
+// Each instance must be registered in its own registry: the collectors of the same metrics conflict otherwise.
+type Metrics struct {
+	// nodeLock guards nodeName, which Setup may change on the default instance while it is in use
+	nodeLock sync.RWMutex
+	nodeName string
 
-func UpdateNodeResourceTopologyWritesMetric(operation, trigger string) {
-	NodeResourceTopologyWrites.With(prometheus.Labels{
-		"node":      nodeName,
+	PodResourceApiCallsFailure         *prometheus.CounterVec
+	NodeResourceTopologyWrites         *prometheus.CounterVec
+	OperationDelay                     *prometheus.GaugeVec
+	WakeupDelay                        *prometheus.GaugeVec
+	NodeResourceTopologyPatchFailure   *prometheus.CounterVec
+	NodeResourceTopologyPatchSizeRatio *prometheus.HistogramVec
+	ScanSequence                       *prometheus.GaugeVec
+	ScanTimestamp                      *prometheus.GaugeVec
+	PublishedScanSequence              *prometheus.GaugeVec
+	ZoneResourceCapacity               *prometheus.GaugeVec
+	ZoneResourceAllocatable            *prometheus.GaugeVec
+	ZoneResourceAvailable              *prometheus.GaugeVec
+	ZoneResourceLargestFree            *prometheus.GaugeVec
+	ZoneResourceStrandedRatio          *prometheus.GaugeVec
+	MetricsTLSCertExpiry               *prometheus.GaugeVec
+	PodResourceAllocation              *prometheus.GaugeVec
+	PodResourceAllocationDropped       *prometheus.GaugeVec
+
+	// the buckets of the latency histograms are configurable, see WithLatencyBuckets
+	PodResourcesAPICallDuration *prometheus.HistogramVec
+	ScanDuration                *prometheus.HistogramVec
+	PublishDuration             *prometheus.HistogramVec
+	WakeupJitter                *prometheus.HistogramVec
+
+	zoneResourcesLock sync.Mutex
+	// zoneResourcesSeen tracks the series set by the last update, to delete the ones which went away
+	zoneResourcesSeen map[zoneResourceKey]struct{}
+
+	fragmentationLock sync.Mutex
+	// fragmentationSeen tracks the resources set by the last update, to delete the ones which went away
+	fragmentationSeen map[string]struct{}
+
+	podAllocationsLock sync.Mutex
+	// podAllocationsSeen tracks the series set by the last update, to delete the ones of the deleted pods
+	podAllocationsSeen map[podAllocationKey]struct{}
+}
+
+// New creates the collectors of the exporter instance running on the given node, registering them in reg.
+// If reg is nil the collectors are not registered. The latency histograms use DefaultLatencyBuckets,
+// unless configured otherwise with WithLatencyBuckets.
+func New(nodeName string, reg prometheus.Registerer, options ...func(*Metrics)) (*Metrics, error) {
+	m := &Metrics{
+		nodeName: nodeName,
+
+		PodResourceApiCallsFailure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rte_podresource_api_call_failures_total",
+			Help: "The total number of podresource api calls that failed by the updater",
+		}, []string{"node", "function_name"}),
+
+		NodeResourceTopologyWrites: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rte_noderesourcetopology_writes_total",
+			Help: "The total number of NodeResourceTopology writes",
+		}, []string{"node", "operation", "trigger"}),
+
+		OperationDelay: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_operation_delay_milliseconds",
+			Help: "The latency between exporting stages, milliseconds",
+		}, []string{"node", "operation_name", "trigger"}),
+
+		WakeupDelay: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_wakeup_delay_milliseconds",
+			Help: "The wakeup delay of the monitor code, milliseconds",
+		}, []string{"node", "trigger"}),
+
+		NodeResourceTopologyPatchFailure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rte_noderesourcetopology_patch_failures_total",
+			Help: "The total number of times the NodeResourceTopology patching failed",
+		}, []string{"node", "trigger"}),
+
+		NodeResourceTopologyPatchSizeRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rte_noderesourcetopology_patch_size_ratio",
+			Help:    "The ratio of patch size to full object size (0.0 to 1.0)",
+			Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0},
+		}, []string{"node"}),
+
+		ScanSequence: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_scan_sequence",
+			Help: "The sequence number of the last scan attempt",
+		}, []string{"node"}),
+
+		ScanTimestamp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_scan_timestamp_seconds",
+			Help: "The completion time of the last successful scan, seconds since the epoch",
+		}, []string{"node"}),
+
+		PublishedScanSequence: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_published_scan_sequence",
+			Help: "The sequence number of the last scan successfully published",
+		}, []string{"node"}),
+
+		ZoneResourceCapacity: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_zone_resource_capacity",
+			Help: "The capacity of a resource in a topology zone",
+		}, []string{"node", "zone", "resource"}),
+
+		ZoneResourceAllocatable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_zone_resource_allocatable",
+			Help: "The allocatable amount of a resource in a topology zone",
+		}, []string{"node", "zone", "resource"}),
+
+		ZoneResourceAvailable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_zone_resource_available",
+			Help: "The available amount of a resource in a topology zone",
+		}, []string{"node", "zone", "resource"}),
+
+		ZoneResourceLargestFree: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_zone_resource_largest_free",
+			Help: "The largest available amount of a resource in a single topology zone",
+		}, []string{"node", "resource"}),
+
+		ZoneResourceStrandedRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_zone_resource_stranded_ratio",
+			Help: "The fraction of the available amount of a resource outside the zone with the largest available amount (0.0 to 1.0)",
+		}, []string{"node", "resource"}),
+
+		MetricsTLSCertExpiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_metrics_tls_cert_expiry_timestamp_seconds",
+			Help: "The expiration time of the certificate serving the metrics, seconds since the epoch",
+		}, []string{"node"}),
+
+		PodResourceAllocation: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_pod_resource_allocation",
+			Help: "The amount of a resource exclusively allocated to a container in a topology zone",
+		}, []string{"node", "namespace", "pod", "container", "zone", "resource"}),
+
+		PodResourceAllocationDropped: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rte_pod_resource_allocation_dropped_series",
+			Help: "The number of pod resource allocation series not exported in the last update because of the cardinality limit",
+		}, []string{"node"}),
+	}
+
+	m.setLatencyBuckets(DefaultLatencyBuckets)
+	for _, opt := range options {
+		opt(m)
+	}
+
+	if reg != nil {
+		collectors := []prometheus.Collector{
+			m.PodResourceApiCallsFailure,
+			m.NodeResourceTopologyWrites,
+			m.OperationDelay,
+			m.WakeupDelay,
+			m.NodeResourceTopologyPatchFailure,
+			m.NodeResourceTopologyPatchSizeRatio,
+			m.ScanSequence,
+			m.ScanTimestamp,
+			m.PublishedScanSequence,
+			m.ZoneResourceCapacity,
+			m.ZoneResourceAllocatable,
+			m.ZoneResourceAvailable,
+			m.ZoneResourceLargestFree,
+			m.ZoneResourceStrandedRatio,
+			m.MetricsTLSCertExpiry,
+			m.PodResourceAllocation,
+			m.PodResourceAllocationDropped,
+			m.PodResourcesAPICallDuration,
+			m.ScanDuration,
+			m.PublishDuration,
+			m.WakeupJitter,
+		}
+		for idx, coll := range collectors {
+			err := reg.Register(coll)
+			if err != nil {
+				// all or nothing, so the registry is usable by another instance
+				for _, done := range collectors[:idx] {
+					reg.Unregister(done)
+				}
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// WithLatencyBuckets sets the buckets of the latency histograms. No buckets select DefaultLatencyBuckets.
+func WithLatencyBuckets(buckets []float64) func(*Metrics) {
+	return func(m *Metrics) {
+		if len(buckets) == 0 {
+			return
+		}
+		m.setLatencyBuckets(buckets)
+	}
+}
+
+func (m *Metrics) NodeName() string {
+	m.nodeLock.RLock()
+	defer m.nodeLock.RUnlock()
+	return m.nodeName
+}
+
+func (m *Metrics) setNodeName(nodeName string) {
+	m.nodeLock.Lock()
+	defer m.nodeLock.Unlock()
+	m.nodeName = nodeName
+}
+
+func (m *Metrics) UpdateNodeResourceTopologyWritesMetric(operation, trigger string) {
+	m.NodeResourceTopologyWrites.With(prometheus.Labels{
+		"node":      m.NodeName(),
 		"operation": operation,
 		"trigger":   trigger,
 	}).Inc()
 }
 
-func UpdatePodResourceApiCallsFailuresMetric(funcName string) {
-	PodResourceApiCallsFailure.With(prometheus.Labels{
-		"node":          nodeName,
+func (m *Metrics) UpdatePodResourceApiCallsFailuresMetric(funcName string) {
+	m.PodResourceApiCallsFailure.With(prometheus.Labels{
+		"node":          m.NodeName(),
 		"function_name": funcName,
 	}).Inc()
 }
 
-func UpdateOperationDelayMetric(opName, trigger string, operationDelay float64) {
-	OperationDelay.With(prometheus.Labels{
-		"node":           nodeName,
+func (m *Metrics) UpdateOperationDelayMetric(opName, trigger string, operationDelay float64) {
+	m.OperationDelay.With(prometheus.Labels{
+		"node":           m.NodeName(),
 		"operation_name": opName,
 		"trigger":        trigger,
 	}).Set(operationDelay)
 }
 
-func UpdateWakeupDelayMetric(trigger string, wakeupDelay float64) {
-	WakeupDelay.With(prometheus.Labels{
-		"node":    nodeName,
+func (m *Metrics) UpdateWakeupDelayMetric(trigger string, wakeupDelay float64) {
+	m.WakeupDelay.With(prometheus.Labels{
+		"node":    m.NodeName(),
 		"trigger": trigger,
 	}).Set(wakeupDelay)
 }
 
-func UpdateNodeResourceTopologyPatchFailuresMetric(trigger string) {
-	NodeResourceTopologyPatchFailure.With(prometheus.Labels{
-		"node":    nodeName,
+func (m *Metrics) UpdateNodeResourceTopologyPatchFailuresMetric(trigger string) {
+	m.NodeResourceTopologyPatchFailure.With(prometheus.Labels{
+		"node":    m.NodeName(),
 		"trigger": trigger,
 	}).Inc()
 }
 
-func UpdateNodeResourceTopologyPatchSizeRatioMetric(ratio float64) {
-	NodeResourceTopologyPatchSizeRatio.With(prometheus.Labels{
-		"node": nodeName,
+func (m *Metrics) UpdateNodeResourceTopologyPatchSizeRatioMetric(ratio float64) {
+	m.NodeResourceTopologyPatchSizeRatio.With(prometheus.Labels{
+		"node": m.NodeName(),
 	}).Observe(ratio)
 }
 
-func Setup(nname string) error {
-	var err error
-	var ok bool
-	var val string = nname
-	if val == "" {
-		val, ok = os.LookupEnv("NODE_NAME")
-		if !ok {
-			val, err = os.Hostname()
+func (m *Metrics) UpdateScanSequenceMetric(seq uint64) {
+	m.ScanSequence.With(prometheus.Labels{
+		"node": m.NodeName(),
+	}).Set(float64(seq))
+}
+
+func (m *Metrics) UpdateScanTimestampMetric(ts time.Time) {
+	m.ScanTimestamp.With(prometheus.Labels{
+		"node": m.NodeName(),
+	}).Set(float64(ts.UnixNano()) / 1e9)
+}
+
+func (m *Metrics) UpdatePublishedScanSequenceMetric(seq uint64) {
+	m.PublishedScanSequence.With(prometheus.Labels{
+		"node": m.NodeName(),
+	}).Set(float64(seq))
+}
+
+// UpdateZoneResourcesMetric sets the zone resource gauges from the given zones. The series
+// of the zones and resources not reported anymore are deleted.
+func (m *Metrics) UpdateZoneResourcesMetric(zones v1alpha2.ZoneList) {
+	m.zoneResourcesLock.Lock()
+	defer m.zoneResourcesLock.Unlock()
+
+	nodeName := m.NodeName()
+	seen := make(map[zoneResourceKey]struct{})
+	for _, zone := range zones {
+		for _, res := range zone.Resources {
+			labels := prometheus.Labels{
+				"node":     nodeName,
+				"zone":     zone.Name,
+				"resource": res.Name,
+			}
+			m.ZoneResourceCapacity.With(labels).Set(res.Capacity.AsApproximateFloat64())
+			m.ZoneResourceAllocatable.With(labels).Set(res.Allocatable.AsApproximateFloat64())
+			m.ZoneResourceAvailable.With(labels).Set(res.Available.AsApproximateFloat64())
+			seen[zoneResourceKey{zone: zone.Name, resource: res.Name}] = struct{}{}
 		}
 	}
-	if err != nil {
-		return err
+	for key := range m.zoneResourcesSeen {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		labels := prometheus.Labels{
+			"node":     nodeName,
+			"zone":     key.zone,
+			"resource": key.resource,
+		}
+		m.ZoneResourceCapacity.Delete(labels)
+		m.ZoneResourceAllocatable.Delete(labels)
+		m.ZoneResourceAvailable.Delete(labels)
+	}
+	m.zoneResourcesSeen = seen
+}
+
+func (m *Metrics) UpdateMetricsTLSCertExpiryMetric(ts time.Time) {
+	m.MetricsTLSCertExpiry.With(prometheus.Labels{
+		"node": m.NodeName(),
+	}).Set(float64(ts.Unix()))
+}
+
+// UpdateFragmentationMetric sets the fragmentation gauges from the given indicators. The series
+// of the resources not reported anymore are deleted.
+func (m *Metrics) UpdateFragmentationMetric(frags []ResourceFragmentation) {
+	m.fragmentationLock.Lock()
+	defer m.fragmentationLock.Unlock()
+
+	nodeName := m.NodeName()
+	seen := make(map[string]struct{})
+	for _, fr := range frags {
+		labels := prometheus.Labels{
+			"node":     nodeName,
+			"resource": fr.Resource,
+		}
+		m.ZoneResourceLargestFree.With(labels).Set(float64(fr.LargestFree))
+		m.ZoneResourceStrandedRatio.With(labels).Set(fr.StrandedRatio)
+		seen[fr.Resource] = struct{}{}
+	}
+	for resource := range m.fragmentationSeen {
+		if _, ok := seen[resource]; ok {
+			continue
+		}
+		labels := prometheus.Labels{
+			"node":     nodeName,
+			"resource": resource,
+		}
+		m.ZoneResourceLargestFree.Delete(labels)
+		m.ZoneResourceStrandedRatio.Delete(labels)
+	}
+	m.fragmentationSeen = seen
+}
+
+// UpdatePodAllocationsMetric sets the pod resource allocation gauge from the given allocations,
+// exporting at most maxSeries series (DefaultPodAllocationMaxSeries if not positive). The series
+// are selected in namespace, pod, container, zone and resource order, so the selection is stable
+// across updates. The series not reported anymore, e.g. of the deleted pods, are deleted.
+func (m *Metrics) UpdatePodAllocationsMetric(allocs []PodAllocation, maxSeries int) {
+	if maxSeries <= 0 {
+		maxSeries = DefaultPodAllocationMaxSeries
+	}
+	keys := make([]podAllocationKey, 0, len(allocs))
+	amounts := make(map[podAllocationKey]int64, len(allocs))
+	for _, alloc := range allocs {
+		key := podAllocationKey{
+			namespace: alloc.Namespace,
+			pod:       alloc.Pod,
+			container: alloc.Container,
+			zone:      alloc.Zone,
+			resource:  alloc.Resource,
+		}
+		if _, ok := amounts[key]; !ok {
+			keys = append(keys, key)
+		}
+		amounts[key] += alloc.Amount
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+	dropped := 0
+	if len(keys) > maxSeries {
+		dropped = len(keys) - maxSeries
+		keys = keys[:maxSeries]
+	}
+
+	m.podAllocationsLock.Lock()
+	defer m.podAllocationsLock.Unlock()
+
+	nodeName := m.NodeName()
+	seen := make(map[podAllocationKey]struct{}, len(keys))
+	for _, key := range keys {
+		m.PodResourceAllocation.With(key.labels(nodeName)).Set(float64(amounts[key]))
+		seen[key] = struct{}{}
+	}
+	for key := range m.podAllocationsSeen {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		m.PodResourceAllocation.Delete(key.labels(nodeName))
+	}
+	m.podAllocationsSeen = seen
+
+	m.PodResourceAllocationDropped.With(prometheus.Labels{
+		"node": nodeName,
+	}).Set(float64(dropped))
+}
+
+func (key podAllocationKey) labels(nodeName string) prometheus.Labels {
+	return prometheus.Labels{
+		"node":      nodeName,
+		"namespace": key.namespace,
+		"pod":       key.pod,
+		"container": key.container,
+		"zone":      key.zone,
+		"resource":  key.resource,
 	}
-	nodeName = val
-	return nil
 }
 
-// GetNodeName is meant for testing purposes
-func GetNodeName() string {
-	return nodeName
+func (key podAllocationKey) less(other podAllocationKey) bool {
+	if key.namespace != other.namespace {
+		return key.namespace < other.namespace
+	}
+	if key.pod != other.pod {
+		return key.pod < other.pod
+	}
+	if key.container != other.container {
+		return key.container < other.container
+	}
+	if key.zone != other.zone {
+		return key.zone < other.zone
+	}
+	return key.resource < other.resource
+}
+
+func (m *Metrics) ObservePodResourcesAPICallDuration(funcName string, elapsed time.Duration) {
+	m.PodResourcesAPICallDuration.With(prometheus.Labels{
+		"node":          m.NodeName(),
+		"function_name": funcName,
+	}).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObserveScanDuration(trigger string, elapsed time.Duration) {
+	m.ScanDuration.With(prometheus.Labels{
+		"node":    m.NodeName(),
+		"trigger": trigger,
+	}).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObservePublishDuration(operation, trigger string, elapsed time.Duration) {
+	m.PublishDuration.With(prometheus.Labels{
+		"node":      m.NodeName(),
+		"operation": operation,
+		"trigger":   trigger,
+	}).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObserveWakeupJitter(jitter time.Duration) {
+	m.WakeupJitter.With(prometheus.Labels{
+		"node": m.NodeName(),
+	}).Observe(jitter.Seconds())
+}
+
+// ParseLatencyBuckets parses a comma-separated list of bucket upper bounds, in seconds.
+// The bounds must be positive and strictly increasing. An empty value selects the defaults.
+func ParseLatencyBuckets(value string) ([]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var buckets []float64
+	for _, item := range strings.Split(value, ",") {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %w", item, err)
+		}
+		if bound <= 0 {
+			return nil, fmt.Errorf("invalid bucket %v: must be positive", bound)
+		}
+		if len(buckets) > 0 && bound <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("invalid bucket %v: buckets must be strictly increasing", bound)
+		}
+		buckets = append(buckets, bound)
+	}
+	return buckets, nil
+}
+
+func (m *Metrics) setLatencyBuckets(buckets []float64) {
+	m.PodResourcesAPICallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rte_podresources_api_call_duration_seconds",
+		Help:    "The latency of the podresources API calls, seconds",
+		Buckets: buckets,
+	}, []string{"node", "function_name"})
+	m.ScanDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rte_scan_duration_seconds",
+		Help:    "The duration of the resources scan, seconds",
+		Buckets: buckets,
+	}, []string{"node", "trigger"})
+	m.PublishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rte_publish_duration_seconds",
+		Help:    "The duration of the topology data publish operations, seconds",
+		Buckets: buckets,
+	}, []string{"node", "operation", "trigger"})
+	m.WakeupJitter = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rte_wakeup_jitter_seconds",
+		Help:    "The difference between the actual and the expected interval of the periodic wakeups, seconds",
+		Buckets: buckets,
+	}, []string{"node"})
 }
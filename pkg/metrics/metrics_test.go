@@ -18,7 +18,17 @@ package metrics
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/resource"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 )
 
 func TestSetup(t *testing.T) {
@@ -68,6 +78,26 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+func TestSetupConcurrentWithUpdates(t *testing.T) {
+	m := Default()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			m.UpdateNodeResourceTopologyWritesMetric("create", "periodic")
+		}
+	}()
+	for range 100 {
+		if err := Setup("race.localtest.it"); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+	}
+	<-done
+	if got := m.NodeName(); got != "race.localtest.it" {
+		t.Errorf("invalid node name: got %q", got)
+	}
+}
+
 func mustHostname() string {
 	val, err := os.Hostname()
 	if err != nil {
@@ -75,3 +105,302 @@ func mustHostname() string {
 	}
 	return val
 }
+
+func TestUpdateZoneResourcesMetric(t *testing.T) {
+	err := Setup("node-a")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	UpdateZoneResourcesMetric(v1alpha2.ZoneList{
+		{
+			Name: "node-0",
+			Type: "Node",
+			Resources: v1alpha2.ResourceInfoList{
+				makeResourceInfo("cpu", "16", "14", "10"),
+				makeResourceInfo("memory", "32Gi", "30Gi", "20Gi"),
+			},
+		},
+		{
+			Name: "node-1",
+			Type: "Node",
+			Resources: v1alpha2.ResourceInfoList{
+				makeResourceInfo("cpu", "16", "16", "16"),
+			},
+		},
+	})
+
+	expected := `
+# HELP rte_zone_resource_available The available amount of a resource in a topology zone
+# TYPE rte_zone_resource_available gauge
+rte_zone_resource_available{node="node-a",resource="cpu",zone="node-0"} 10
+rte_zone_resource_available{node="node-a",resource="cpu",zone="node-1"} 16
+rte_zone_resource_available{node="node-a",resource="memory",zone="node-0"} 2.147483648e+10
+`
+	err = testutil.CollectAndCompare(Default().ZoneResourceAvailable, strings.NewReader(expected))
+	if err != nil {
+		t.Fatalf("unexpected available metrics: %v", err)
+	}
+	if got := testutil.ToFloat64(Default().ZoneResourceCapacity.WithLabelValues("node-a", "node-0", "cpu")); got != 16 {
+		t.Errorf("unexpected capacity: got %v expected 16", got)
+	}
+	if got := testutil.ToFloat64(Default().ZoneResourceAllocatable.WithLabelValues("node-a", "node-0", "cpu")); got != 14 {
+		t.Errorf("unexpected allocatable: got %v expected 14", got)
+	}
+
+	// the series of the zones gone away must be deleted
+	UpdateZoneResourcesMetric(v1alpha2.ZoneList{
+		{
+			Name: "node-0",
+			Type: "Node",
+			Resources: v1alpha2.ResourceInfoList{
+				makeResourceInfo("cpu", "16", "14", "12"),
+			},
+		},
+	})
+	for _, gauge := range []*prometheus.GaugeVec{Default().ZoneResourceCapacity, Default().ZoneResourceAllocatable, Default().ZoneResourceAvailable} {
+		if got := testutil.CollectAndCount(gauge); got != 1 {
+			t.Errorf("unexpected series count: got %d expected 1", got)
+		}
+	}
+	if got := testutil.ToFloat64(Default().ZoneResourceAvailable.WithLabelValues("node-a", "node-0", "cpu")); got != 12 {
+		t.Errorf("unexpected available: got %v expected 12", got)
+	}
+}
+
+func TestUpdateFragmentationMetric(t *testing.T) {
+	m, err := New("node-a", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	m.UpdateFragmentationMetric([]ResourceFragmentation{
+		{Resource: "cpu", LargestFree: 10, StrandedRatio: 0.5},
+		{Resource: "fake.io/gpu", LargestFree: 1, StrandedRatio: 0},
+	})
+	for _, gauge := range []*prometheus.GaugeVec{m.ZoneResourceLargestFree, m.ZoneResourceStrandedRatio} {
+		if got := testutil.CollectAndCount(gauge); got != 2 {
+			t.Errorf("unexpected series count: got %d expected 2", got)
+		}
+	}
+
+	// the series of the resources gone away must be deleted
+	m.UpdateFragmentationMetric([]ResourceFragmentation{
+		{Resource: "cpu", LargestFree: 12, StrandedRatio: 0.25},
+	})
+	for _, gauge := range []*prometheus.GaugeVec{m.ZoneResourceLargestFree, m.ZoneResourceStrandedRatio} {
+		if got := testutil.CollectAndCount(gauge); got != 1 {
+			t.Errorf("unexpected series count: got %d expected 1", got)
+		}
+	}
+	if got := testutil.ToFloat64(m.ZoneResourceLargestFree.WithLabelValues("node-a", "cpu")); got != 12 {
+		t.Errorf("unexpected largest free: got %v expected 12", got)
+	}
+	if got := testutil.ToFloat64(m.ZoneResourceStrandedRatio.WithLabelValues("node-a", "cpu")); got != 0.25 {
+		t.Errorf("unexpected stranded ratio: got %v expected 0.25", got)
+	}
+
+	m.UpdateFragmentationMetric(nil)
+	if got := testutil.CollectAndCount(m.ZoneResourceLargestFree); got != 0 {
+		t.Errorf("unexpected series count after all resources gone: got %d", got)
+	}
+}
+
+func makeResourceInfo(name, capacity, allocatable, available string) v1alpha2.ResourceInfo {
+	return v1alpha2.ResourceInfo{
+		Name:        name,
+		Capacity:    resource.MustParse(capacity),
+		Allocatable: resource.MustParse(allocatable),
+		Available:   resource.MustParse(available),
+	}
+}
+
+func TestUpdatePodAllocationsMetric(t *testing.T) {
+	err := Setup("node-a")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	allocs := []PodAllocation{
+		{Namespace: "ns-a", Pod: "pod-a", Container: "cnt-0", Zone: "node-0", Resource: "cpu", Amount: 2},
+		{Namespace: "ns-a", Pod: "pod-a", Container: "cnt-0", Zone: "node-1", Resource: "cpu", Amount: 1},
+		{Namespace: "ns-b", Pod: "pod-b", Container: "cnt-0", Zone: "node-0", Resource: "memory", Amount: 1024},
+	}
+	UpdatePodAllocationsMetric(allocs, 0)
+	if got := testutil.CollectAndCount(Default().PodResourceAllocation); got != 3 {
+		t.Fatalf("unexpected series count: got %d expected 3", got)
+	}
+	if got := testutil.ToFloat64(Default().PodResourceAllocation.WithLabelValues("node-a", "ns-a", "pod-a", "cnt-0", "node-0", "cpu")); got != 2 {
+		t.Errorf("unexpected allocation: got %v expected 2", got)
+	}
+
+	// the series of the deleted pods must be deleted
+	UpdatePodAllocationsMetric(allocs[2:], 0)
+	if got := testutil.CollectAndCount(Default().PodResourceAllocation); got != 1 {
+		t.Fatalf("unexpected series count after pod deletion: got %d expected 1", got)
+	}
+
+	UpdatePodAllocationsMetric(allocs, 2)
+	if got := testutil.CollectAndCount(Default().PodResourceAllocation); got != 2 {
+		t.Fatalf("unexpected series count with limit: got %d expected 2", got)
+	}
+	// the selection is stable: the series sorting last is dropped
+	if got := testutil.ToFloat64(Default().PodResourceAllocationDropped.WithLabelValues("node-a")); got != 1 {
+		t.Errorf("unexpected dropped series: got %v expected 1", got)
+	}
+	if got := testutil.ToFloat64(Default().PodResourceAllocation.WithLabelValues("node-a", "ns-a", "pod-a", "cnt-0", "node-1", "cpu")); got != 1 {
+		t.Errorf("unexpected allocation: got %v expected 1", got)
+	}
+}
+
+func TestParseLatencyBuckets(t *testing.T) {
+	type testCase struct {
+		name          string
+		value         string
+		expected      []float64
+		expectedError bool
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name: "empty",
+		},
+		{
+			name:     "valid",
+			value:    "0.01, 0.1,1,10",
+			expected: []float64{0.01, 0.1, 1, 10},
+		},
+		{
+			name:          "not a number",
+			value:         "0.1,foo",
+			expectedError: true,
+		},
+		{
+			name:          "not positive",
+			value:         "0,1",
+			expectedError: true,
+		},
+		{
+			name:          "not increasing",
+			value:         "0.1,1,1",
+			expectedError: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := ParseLatencyBuckets(tcase.value)
+			gotErr := (err != nil)
+			if gotErr != tcase.expectedError {
+				t.Fatalf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+			if !reflect.DeepEqual(got, tcase.expected) {
+				t.Errorf("unexpected buckets: got %v expected %v", got, tcase.expected)
+			}
+		})
+	}
+}
+
+func TestLatencyBuckets(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := New("node-a", reg, WithLatencyBuckets([]float64{0.5, 1}))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	m.ObservePublishDuration("update", "periodic", 700*time.Millisecond)
+
+	expected := `
+# HELP rte_publish_duration_seconds The duration of the topology data publish operations, seconds
+# TYPE rte_publish_duration_seconds histogram
+rte_publish_duration_seconds_bucket{node="node-a",operation="update",trigger="periodic",le="0.5"} 0
+rte_publish_duration_seconds_bucket{node="node-a",operation="update",trigger="periodic",le="1"} 1
+rte_publish_duration_seconds_bucket{node="node-a",operation="update",trigger="periodic",le="+Inf"} 1
+rte_publish_duration_seconds_sum{node="node-a",operation="update",trigger="periodic"} 0.7
+rte_publish_duration_seconds_count{node="node-a",operation="update",trigger="periodic"} 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "rte_publish_duration_seconds")
+	if err != nil {
+		t.Errorf("unexpected histogram: %v", err)
+	}
+}
+
+func TestDefaultInstanceServed(t *testing.T) {
+	err := Setup("node-a")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	UpdatePublishedScanSequenceMetric(4)
+
+	expected := `
+# HELP rte_published_scan_sequence The sequence number of the last scan successfully published
+# TYPE rte_published_scan_sequence gauge
+rte_published_scan_sequence{node="node-a"} 4
+`
+	err = testutil.GatherAndCompare(ctrlmetrics.Registry, strings.NewReader(expected), "rte_published_scan_sequence")
+	if err != nil {
+		t.Errorf("unexpected metrics: %v", err)
+	}
+}
+
+func TestInstancesAreIndependent(t *testing.T) {
+	regA := prometheus.NewRegistry()
+	mA, err := New("node-a", regA)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	regB := prometheus.NewRegistry()
+	mB, err := New("node-b", regB)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	mA.UpdateScanSequenceMetric(3)
+	mB.UpdateScanSequenceMetric(7)
+	mA.ObserveScanDuration("periodic", 10*time.Millisecond)
+
+	expectedA := `
+# HELP rte_scan_sequence The sequence number of the last scan attempt
+# TYPE rte_scan_sequence gauge
+rte_scan_sequence{node="node-a"} 3
+`
+	err = testutil.GatherAndCompare(regA, strings.NewReader(expectedA), "rte_scan_sequence")
+	if err != nil {
+		t.Errorf("unexpected metrics of node-a: %v", err)
+	}
+	expectedB := `
+# HELP rte_scan_sequence The sequence number of the last scan attempt
+# TYPE rte_scan_sequence gauge
+rte_scan_sequence{node="node-b"} 7
+`
+	err = testutil.GatherAndCompare(regB, strings.NewReader(expectedB), "rte_scan_sequence")
+	if err != nil {
+		t.Errorf("unexpected metrics of node-b: %v", err)
+	}
+	if got := testutil.CollectAndCount(mB.ScanDuration); got != 0 {
+		t.Errorf("observation leaked across instances: got %d series", got)
+	}
+
+	_, err = New("node-c", regA)
+	if err == nil {
+		t.Errorf("expected error registering twice in the same registry")
+	}
+}
+
+func TestUnregisteredInstance(t *testing.T) {
+	m, err := New("node-a", nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	m.UpdateZoneResourcesMetric(v1alpha2.ZoneList{
+		{
+			Name:      "node-0",
+			Type:      "Node",
+			Resources: v1alpha2.ResourceInfoList{makeResourceInfo("cpu", "16", "14", "10")},
+		},
+	})
+	if got := testutil.ToFloat64(m.ZoneResourceAvailable.WithLabelValues("node-a", "node-0", "cpu")); got != 10 {
+		t.Errorf("unexpected available: got %v expected 10", got)
+	}
+	if m.ZoneResourceAvailable == Default().ZoneResourceAvailable {
+		t.Errorf("the instance shares the collectors of the default one")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	rtemetrics "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
+)
+
+// certReloadInterval is how often the certificate and the client CA bundle are checked for changes
+const certReloadInterval = 10 * time.Second
+
+// certReloader tracks the expiry of the metrics TLS certificate and serves the client CA bundle,
+// reloading it when the file changes. The certificate itself is reloaded by the controller-runtime
+// metrics server, which watches the files when no GetCertificate is configured.
+type certReloader struct {
+	certFile string
+	certData []byte
+	metrics  *rtemetrics.Metrics
+	interval time.Duration
+
+	caFile string
+	lock   sync.RWMutex
+	caData []byte
+	caPool *x509.CertPool
+}
+
+// newCertReloader builds a reloader for the given certificate and client CA bundle.
+// An empty caFile disables the client CA handling.
+func newCertReloader(certFile, caFile string, m *rtemetrics.Metrics) (*certReloader, error) {
+	cr := &certReloader{
+		certFile: certFile,
+		metrics:  m,
+		interval: certReloadInterval,
+		caFile:   caFile,
+	}
+	cr.reloadCertExpiry()
+	if cr.caFile != "" {
+		if _, err := cr.reloadCA(); err != nil {
+			return nil, err
+		}
+	}
+	return cr, nil
+}
+
+// reloadCertExpiry updates the expiry metric if the certificate changed. If the certificate is missing,
+// the server falls back to a self-signed certificate, whose expiry is not tracked.
+func (cr *certReloader) reloadCertExpiry() {
+	data, err := os.ReadFile(cr.certFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		klog.Warningf("cannot read the metrics certificate: %v", err)
+		return
+	}
+	if bytes.Equal(data, cr.certData) {
+		return
+	}
+	cr.certData = data
+	block, _ := pem.Decode(data)
+	if block == nil {
+		klog.Warningf("no PEM data in the metrics certificate %q", cr.certFile)
+		return
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		klog.Warningf("cannot parse the metrics certificate: %v", err)
+		return
+	}
+	klog.V(2).Infof("metrics certificate loaded, expires at %v", leaf.NotAfter)
+	cr.metrics.UpdateMetricsTLSCertExpiryMetric(leaf.NotAfter)
+}
+
+// reloadCA reads the client CA bundle, and replaces the current one if changed.
+// On error, the current bundle is kept. Returns true if the bundle was replaced.
+func (cr *certReloader) reloadCA() (bool, error) {
+	data, err := os.ReadFile(cr.caFile)
+	if err != nil {
+		return false, fmt.Errorf("cannot read the metrics client CA file: %w", err)
+	}
+
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	if bytes.Equal(data, cr.caData) {
+		return false, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return false, fmt.Errorf("no valid certificates in the metrics client CA file %q", cr.caFile)
+	}
+	cr.caData = data
+	cr.caPool = pool
+	return true, nil
+}
+
+func (cr *certReloader) clientCAs() *x509.CertPool {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+	return cr.caPool
+}
+
+// Start watches the files until the context is cancelled.
+func (cr *certReloader) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(cr.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cr.reloadCertExpiry()
+				if cr.caFile == "" {
+					continue
+				}
+				updated, err := cr.reloadCA()
+				if err != nil {
+					klog.Warningf("failed to reload the metrics client CA, keeping the current one: %v", err)
+					continue
+				}
+				if updated {
+					klog.Infof("metrics client CA reloaded from %q", cr.caFile)
+				}
+			}
+		}
+	}()
+}
+
+// TLSOpt makes the TLS configuration use the current client CA bundle.
+// It leaves GetCertificate unset, so the server keeps watching the certificate files.
+func (cr *certReloader) TLSOpt() func(*tls.Config) {
+	return func(cfg *tls.Config) {
+		if cr.caFile == "" {
+			return
+		}
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientCfg := cfg.Clone()
+			clientCfg.GetConfigForClient = nil
+			clientCfg.ClientCAs = cr.clientCAs()
+			return clientCfg, nil
+		}
+	}
+}
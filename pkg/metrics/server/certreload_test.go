@@ -0,0 +1,201 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	rtemetrics "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
+)
+
+func TestCertReloaderTracksCertExpiry(t *testing.T) {
+	dir := t.TempDir()
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	certFile := writeTestKeyPair(t, dir, "rte-first", expiry)
+
+	m := newTestMetrics(t)
+	cr, err := newCertReloader(certFile, "", m)
+	if err != nil {
+		t.Fatalf("failed to create the reloader: %v", err)
+	}
+	checkCertExpiryMetric(t, m, expiry)
+
+	expiry2 := expiry.Add(24 * time.Hour)
+	writeTestKeyPair(t, dir, "rte-second", expiry2)
+	cr.reloadCertExpiry()
+	checkCertExpiryMetric(t, m, expiry2)
+
+	cfg := &tls.Config{}
+	cr.TLSOpt()(cfg)
+	if cfg.GetCertificate != nil || cfg.GetConfigForClient != nil {
+		t.Errorf("expected the TLS config untouched, got %+v", cfg)
+	}
+}
+
+func TestCertReloaderMissingCertificate(t *testing.T) {
+	m := newTestMetrics(t)
+	_, err := newCertReloader(filepath.Join(t.TempDir(), "tls.crt"), "", m)
+	if err != nil {
+		t.Fatalf("failed to create the reloader: %v", err)
+	}
+	if got := testutil.CollectAndCount(m.MetricsTLSCertExpiry); got != 0 {
+		t.Errorf("unexpected cert expiry series: %d", got)
+	}
+}
+
+func TestCertReloaderReloadsClientCA(t *testing.T) {
+	caFile := writeTestKeyPair(t, t.TempDir(), "rte-ca-first", time.Now().Add(time.Hour))
+
+	cr, err := newCertReloader(filepath.Join(t.TempDir(), "tls.crt"), caFile, newTestMetrics(t))
+	if err != nil {
+		t.Fatalf("failed to create the reloader: %v", err)
+	}
+	cfg := &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven}
+	cr.TLSOpt()(cfg)
+	if cfg.GetCertificate != nil {
+		t.Errorf("the certificate must be left to the server certificate watcher")
+	}
+	checkClientCASubject(t, cfg, "rte-ca-first")
+
+	updated, err := cr.reloadCA()
+	if err != nil || updated {
+		t.Errorf("unchanged CA: got updated=%v err=%v", updated, err)
+	}
+
+	writeTestKeyPair(t, filepath.Dir(caFile), "rte-ca-second", time.Now().Add(time.Hour))
+	updated, err = cr.reloadCA()
+	if err != nil || !updated {
+		t.Fatalf("changed CA: got updated=%v err=%v", updated, err)
+	}
+	checkClientCASubject(t, cfg, "rte-ca-second")
+
+	err = os.WriteFile(caFile, []byte("garbage"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write the CA file: %v", err)
+	}
+	_, err = cr.reloadCA()
+	if err == nil {
+		t.Errorf("expected error loading an invalid CA")
+	}
+	checkClientCASubject(t, cfg, "rte-ca-second")
+}
+
+func TestCertReloaderInvalidClientCA(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	err := os.WriteFile(caFile, []byte("garbage"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write the CA file: %v", err)
+	}
+	_, err = newCertReloader(filepath.Join(t.TempDir(), "tls.crt"), caFile, newTestMetrics(t))
+	if err == nil {
+		t.Errorf("expected error with an invalid client CA")
+	}
+}
+
+func newTestMetrics(t *testing.T) *rtemetrics.Metrics {
+	t.Helper()
+	m, err := rtemetrics.New("test-node", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("failed to create the metrics: %v", err)
+	}
+	return m
+}
+
+func checkCertExpiryMetric(t *testing.T, m *rtemetrics.Metrics, expected time.Time) {
+	t.Helper()
+	got := testutil.ToFloat64(m.MetricsTLSCertExpiry)
+	if got != float64(expected.Unix()) {
+		t.Errorf("cert expiry metric %v expected %v", got, expected.Unix())
+	}
+}
+
+func checkClientCASubject(t *testing.T, cfg *tls.Config, expected string) {
+	t.Helper()
+	clientCfg, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("failed to get the client config: %v", err)
+	}
+	if clientCfg.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Errorf("client auth not preserved: %v", clientCfg.ClientAuth)
+	}
+	//nolint:staticcheck // Subjects() is fine for pools built from PEM
+	subjects := clientCfg.ClientCAs.Subjects()
+	if len(subjects) != 1 {
+		t.Fatalf("expected one CA, got %d", len(subjects))
+	}
+	var name pkix.RDNSequence
+	if _, err := asn1.Unmarshal(subjects[0], &name); err != nil {
+		t.Fatalf("failed to parse the CA subject: %v", err)
+	}
+	var subj pkix.Name
+	subj.FillFromRDNSequence(&name)
+	if subj.CommonName != expected {
+		t.Errorf("client CA CN %q expected %q", subj.CommonName, expected)
+	}
+}
+
+// writeTestKeyPair writes a self-signed certificate and its key as tls.crt and tls.key, returning the certificate path.
+func writeTestKeyPair(t *testing.T, dir, commonName string, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	certFile := filepath.Join(dir, "tls.crt")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile
+}
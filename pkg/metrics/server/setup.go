@@ -20,12 +20,19 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	ctrlmetricssrv "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/debugstate"
+	rtemetrics "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 )
 
 const (
@@ -43,6 +50,9 @@ const (
 	ServingDisabled = "disabled"
 	ServingHTTP     = "http" // plaintext
 	ServingHTTPTLS  = "httptls"
+	// ServingHTTPTLSAuth is like ServingHTTPTLS, but the requests must carry a bearer token
+	// authenticated with a TokenReview and authorized with a SubjectAccessReview.
+	ServingHTTPTLSAuth = "httptlsauth"
 )
 
 func NewDefaultTLSConfig() TLSConfig {
@@ -62,12 +72,25 @@ type TLSConfig struct {
 	// CipherSuites is a comma-separated list of TLS 1.2 cipher names as accepted by
 	// k8s.io/component-base/cli/flag.TLSCipherSuites (same as kube-apiserver --tls-cipher-suites).
 	CipherSuites string `json:"cipherSuites,omitempty"`
+	// ClientCAFile is the PEM bundle of the CAs trusted to verify the client certificates.
+	// If empty, the system roots are used, so any certificate issued by a public CA is accepted.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+	// AllowedSubjects is a comma-separated list of names. If not empty, only the client certificates
+	// whose subject common name or SANs (DNS names, URIs, email addresses) match one of them are accepted.
+	// Requires ClientCAFile: anyone can get a certificate for a matching name from a public CA.
+	AllowedSubjects string `json:"allowedSubjects,omitempty"`
 }
 
 type Config struct {
 	IP   string
 	Port int
 	TLS  TLSConfig
+	// Debug, if not nil, is served on the endpoints exposing the exporter state, alongside the pprof profiles.
+	Debug *debugstate.State
+	// RestConfig is used to review the tokens of the requests, and it is required with ServingHTTPTLSAuth.
+	RestConfig *rest.Config
+	// Metrics receives the metrics about the server itself, e.g. the certificate expiry.
+	Metrics *rtemetrics.Metrics
 }
 
 func NewConfig(ip string, port int, tlsConf TLSConfig) Config {
@@ -84,12 +107,14 @@ func NewDefaultConfig() Config {
 
 func (conf TLSConfig) Clone() TLSConfig {
 	return TLSConfig{
-		CertsDir:      conf.CertsDir,
-		CertFile:      conf.CertFile,
-		KeyFile:       conf.KeyFile,
-		WantCliAuth:   conf.WantCliAuth,
-		MinTLSVersion: conf.MinTLSVersion,
-		CipherSuites:  conf.CipherSuites,
+		CertsDir:        conf.CertsDir,
+		CertFile:        conf.CertFile,
+		KeyFile:         conf.KeyFile,
+		WantCliAuth:     conf.WantCliAuth,
+		MinTLSVersion:   conf.MinTLSVersion,
+		CipherSuites:    conf.CipherSuites,
+		ClientCAFile:    conf.ClientCAFile,
+		AllowedSubjects: conf.AllowedSubjects,
 	}
 }
 
@@ -113,6 +138,8 @@ func ServingModeIsSupported(value string) (string, error) {
 		return val, nil
 	case ServingHTTPTLS:
 		return val, nil
+	case ServingHTTPTLSAuth:
+		return val, nil
 	default:
 		return val, fmt.Errorf("unsupported method  %q", value)
 	}
@@ -123,6 +150,7 @@ func ServingModeSupported() string {
 		ServingDisabled,
 		ServingHTTP,
 		ServingHTTPTLS,
+		ServingHTTPTLSAuth,
 	}
 	return strings.Join(modes, ",")
 }
@@ -148,6 +176,14 @@ func AddressFromEnv() string {
 	return ip
 }
 
+func ClientCAFileFromEnv() string {
+	path, ok := os.LookupEnv("METRICS_CLIENT_CA_FILE")
+	if !ok {
+		return ""
+	}
+	return path
+}
+
 func Setup(mode string, conf Config) error {
 	if mode == ServingDisabled {
 		klog.Infof("metrics endpoint disabled")
@@ -157,17 +193,25 @@ func Setup(mode string, conf Config) error {
 	if err := conf.Validate(); err != nil {
 		return err
 	}
+	if conf.Metrics == nil {
+		conf.Metrics = rtemetrics.Default()
+	}
 
-	var secureServing bool
+	var secureServing, authServing bool
 	switch mode {
 	case ServingHTTP:
 		secureServing = false
 	case ServingHTTPTLS:
 		secureServing = true
+	case ServingHTTPTLSAuth:
+		secureServing = true
+		authServing = true
 	default:
 		return fmt.Errorf("unknown mode: %v", mode)
 	}
 
+	ctx := context.Background()
+
 	var tlsOpts []func(*tls.Config)
 	if secureServing {
 		var err error
@@ -175,6 +219,12 @@ func Setup(mode string, conf Config) error {
 		if err != nil {
 			return err
 		}
+		reloader, err := newCertReloader(filepath.Join(conf.TLS.CertsDir, conf.TLS.CertFile), conf.TLS.ClientCAFile, conf.Metrics)
+		if err != nil {
+			return err
+		}
+		reloader.Start(ctx)
+		tlsOpts = append(tlsOpts, reloader.TLSOpt())
 	}
 
 	opts := ctrlmetricssrv.Options{
@@ -185,13 +235,29 @@ func Setup(mode string, conf Config) error {
 		KeyName:       conf.TLS.KeyFile,
 		TLSOpts:       tlsOpts,
 	}
-	srv, err := ctrlmetricssrv.NewServer(opts, nil, nil)
+	if conf.Debug != nil {
+		klog.Warningf("debug endpoints enabled: the exporter state and the pprof profiles are served alongside the metrics")
+		opts.ExtraHandlers = conf.Debug.Handlers()
+	}
+	var httpClient *http.Client
+	if authServing {
+		if conf.RestConfig == nil {
+			return fmt.Errorf("missing client configuration for the %s mode", ServingHTTPTLSAuth)
+		}
+		var err error
+		httpClient, err = rest.HTTPClientFor(conf.RestConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build the client for the %s mode: %w", ServingHTTPTLSAuth, err)
+		}
+		opts.FilterProvider = filters.WithAuthenticationAndAuthorization
+		klog.InfoS("metrics server configuration", "authentication and authorization", "enabled")
+	}
+
+	srv, err := ctrlmetricssrv.NewServer(opts, conf.RestConfig, httpClient)
 	if err != nil {
 		return fmt.Errorf("failed to build server with port %d: %w", conf.Port, err)
 	}
 
-	ctx := context.Background()
-
 	go func() {
 		err := srv.Start(ctx)
 		if err != nil {
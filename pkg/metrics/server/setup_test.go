@@ -0,0 +1,102 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestServingModeIsSupported(t *testing.T) {
+	type testCase struct {
+		value         string
+		expected      string
+		expectedError bool
+	}
+
+	for _, tcase := range []testCase{
+		{value: "disabled", expected: ServingDisabled},
+		{value: "HTTP", expected: ServingHTTP},
+		{value: "httptls", expected: ServingHTTPTLS},
+		{value: "httptlsauth", expected: ServingHTTPTLSAuth},
+		{value: "https", expected: "https", expectedError: true},
+	} {
+		t.Run(tcase.value, func(t *testing.T) {
+			got, err := ServingModeIsSupported(tcase.value)
+			gotErr := (err != nil)
+			if gotErr != tcase.expectedError {
+				t.Fatalf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+			if got != tcase.expected {
+				t.Errorf("unexpected mode: got %q expected %q", got, tcase.expected)
+			}
+		})
+	}
+}
+
+func TestSetupAuthRequiresRestConfig(t *testing.T) {
+	conf := NewConfig("127.0.0.1", PortDefault, TLSConfig{CertsDir: t.TempDir()})
+	err := Setup(ServingHTTPTLSAuth, conf)
+	if err == nil {
+		t.Fatalf("setup unexpectedly succeeded without the client configuration")
+	}
+}
+
+func TestSetupAuthRejectsWithoutToken(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	conf := NewConfig("127.0.0.1", port, TLSConfig{CertsDir: t.TempDir(), CertFile: TLSCert, KeyFile: TLSKey})
+	// never contacted: requests without a token are rejected before any TokenReview
+	conf.RestConfig = &rest.Config{Host: "https://127.0.0.1:1"}
+	err = Setup(ServingHTTPTLSAuth, conf)
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	cli := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // self-signed
+		},
+	}
+	var lastErr error
+	for range 50 {
+		resp, err := cli.Get(fmt.Sprintf("https://127.0.0.1:%d/metrics", port))
+		if err != nil {
+			lastErr = err
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("metrics served without a token: %d", resp.StatusCode)
+		}
+		return
+	}
+	t.Fatalf("failed to get the metrics: %v", lastErr)
+}
@@ -18,6 +18,8 @@ package metrics
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -85,6 +87,46 @@ func splitNonEmptyCSV(s string) []string {
 	return out
 }
 
+// clientSubjectPolicyOpt returns a TLS option func which rejects the client certificates whose
+// subject common name and SANs (DNS names, URIs, email addresses) match none of the names in allowedCSV.
+// The match is exact. Empty allowedCSV means no restriction (callers should not append this opt).
+func clientSubjectPolicyOpt(allowedCSV string) func(*tls.Config) {
+	allowed := make(map[string]struct{})
+	for _, name := range splitNonEmptyCSV(allowedCSV) {
+		allowed[name] = struct{}{}
+	}
+	return func(cfg *tls.Config) {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("client certificate required")
+			}
+			leaf := cs.PeerCertificates[0]
+			if !clientCertAllowed(leaf, allowed) {
+				return fmt.Errorf("client certificate subject %q not allowed", leaf.Subject.String())
+			}
+			return nil
+		}
+	}
+}
+
+func clientCertAllowed(cert *x509.Certificate, allowed map[string]struct{}) bool {
+	names := []string{cert.Subject.CommonName}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := allowed[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // withHTTP11 sets ALPN for the metrics HTTP/1.1 listener.
 func withHTTP11() func(*tls.Config) {
 	return func(cfg *tls.Config) {
@@ -93,7 +135,7 @@ func withHTTP11() func(*tls.Config) {
 }
 
 // buildSecureMetricsTLSOpts composes TLSOpts for HTTPS metrics: HTTP/1.1 ALPN, optional
-// TLS version/cipher policy, and client certificate settings.
+// TLS version/cipher policy, client certificate settings and the allowed client subjects, if any.
 func buildSecureMetricsTLSOpts(tlsConf TLSConfig) ([]func(*tls.Config), error) {
 	policyOpts, err := metricsTLSPolicyOpts(tlsConf.MinTLSVersion, tlsConf.CipherSuites)
 	if err != nil {
@@ -102,5 +144,8 @@ func buildSecureMetricsTLSOpts(tlsConf TLSConfig) ([]func(*tls.Config), error) {
 	out := []func(*tls.Config){withHTTP11()}
 	out = append(out, policyOpts...)
 	out = append(out, WithClientAuth(tlsConf.WantCliAuth))
+	if len(splitNonEmptyCSV(tlsConf.AllowedSubjects)) > 0 {
+		out = append(out, clientSubjectPolicyOpt(tlsConf.AllowedSubjects))
+	}
 	return out, nil
 }
@@ -18,6 +18,9 @@ package metrics
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
 	"reflect"
 	"testing"
 )
@@ -212,6 +215,36 @@ func TestBuildSecureMetricsTLSOpts(t *testing.T) {
 				checkClientAuth(t, cfg, tls.RequireAndVerifyClientCert)
 			},
 		},
+		{
+			name: "client certificate required with allowed subjects",
+			tlsConf: TLSConfig{
+				WantCliAuth:     true,
+				AllowedSubjects: "prometheus, metrics.example.com",
+			},
+			wantOptCount: 3,
+			check: func(t *testing.T, cfg *tls.Config) {
+				t.Helper()
+				checkNextProtosHTTP11(t, cfg)
+				checkClientAuth(t, cfg, tls.RequireAndVerifyClientCert)
+				if cfg.VerifyConnection == nil {
+					t.Errorf("VerifyConnection should be set")
+				}
+			},
+		},
+		{
+			name: "blank allowed subjects adds no restriction",
+			tlsConf: TLSConfig{
+				WantCliAuth:     true,
+				AllowedSubjects: " , ",
+			},
+			wantOptCount: 2,
+			check: func(t *testing.T, cfg *tls.Config) {
+				t.Helper()
+				if cfg.VerifyConnection != nil {
+					t.Errorf("VerifyConnection should be unset")
+				}
+			},
+		},
 		{
 			name: "invalid policy surfaces error",
 			tlsConf: TLSConfig{
@@ -237,6 +270,86 @@ func TestBuildSecureMetricsTLSOpts(t *testing.T) {
 	}
 }
 
+func TestClientSubjectPolicyOpt(t *testing.T) {
+	type testCase struct {
+		name      string
+		allowed   string
+		peerCerts []*x509.Certificate
+		expectErr bool
+	}
+
+	spiffeID, err := url.Parse("spiffe://cluster.local/ns/monitoring/sa/prometheus")
+	if err != nil {
+		t.Fatalf("failed to parse the URI: %v", err)
+	}
+
+	testCases := []testCase{
+		{
+			name:      "common name matches",
+			allowed:   "prometheus",
+			peerCerts: []*x509.Certificate{{Subject: pkix.Name{CommonName: "prometheus"}}},
+		},
+		{
+			name:    "DNS SAN matches",
+			allowed: "other, metrics.example.com",
+			peerCerts: []*x509.Certificate{{
+				Subject:  pkix.Name{CommonName: "scraper"},
+				DNSNames: []string{"scraper.example.com", "metrics.example.com"},
+			}},
+		},
+		{
+			name:    "URI SAN matches",
+			allowed: "spiffe://cluster.local/ns/monitoring/sa/prometheus",
+			peerCerts: []*x509.Certificate{{
+				Subject: pkix.Name{CommonName: "scraper"},
+				URIs:    []*url.URL{spiffeID},
+			}},
+		},
+		{
+			name:    "email SAN matches",
+			allowed: "ops@example.com",
+			peerCerts: []*x509.Certificate{{
+				EmailAddresses: []string{"ops@example.com"},
+			}},
+		},
+		{
+			name:    "only the leaf is checked",
+			allowed: "intermediate",
+			peerCerts: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: "scraper"}},
+				{Subject: pkix.Name{CommonName: "intermediate"}},
+			},
+			expectErr: true,
+		},
+		{
+			name:    "partial match is rejected",
+			allowed: "example.com",
+			peerCerts: []*x509.Certificate{{
+				Subject:  pkix.Name{CommonName: "metrics.example.com"},
+				DNSNames: []string{"metrics.example.com"},
+			}},
+			expectErr: true,
+		},
+		{
+			name:      "no client certificate",
+			allowed:   "prometheus",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &tls.Config{}
+			clientSubjectPolicyOpt(tc.allowed)(cfg)
+			err := cfg.VerifyConnection(tls.ConnectionState{PeerCertificates: tc.peerCerts})
+			gotErr := (err != nil)
+			if gotErr != tc.expectErr {
+				t.Errorf("error mismatch: got %v expected %v", err, tc.expectErr)
+			}
+		})
+	}
+}
+
 // checkTLSPolicyOutcome checks error expectations and option count. It returns false if the
 // subtest should not continue (expected error path or fatal test failure).
 func checkTLSPolicyOutcome(t *testing.T, err error, expectErr bool, opts []func(*tls.Config), wantOptCount int) bool {
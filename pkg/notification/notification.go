@@ -1,21 +1,35 @@
 package notification
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/tracing"
 )
 
 // FilterEvent returns true if the given event is relevant and should be handled
 type FilterEvent func(event fsnotify.Event) bool
 
+const (
+	TriggerInitial = "initial"
+	TriggerTimer   = "timer"
+	TriggerFile    = "file"
+)
+
 type Event struct {
 	Timestamp     time.Time
 	TimerInterval time.Duration
+	// SpanContext identifies the trace of the update cycle started by this event
+	SpanContext trace.SpanContext
 }
 
 func (ev Event) IsTimer() bool {
@@ -27,7 +41,7 @@ type EventSource interface {
 	Close()
 	Wait()
 	Stop()
-	Run()
+	Run(ctx context.Context)
 }
 type UnlimitedEventSource struct {
 	sleepInterval time.Duration
@@ -35,6 +49,7 @@ type UnlimitedEventSource struct {
 	watcher       *fsnotify.Watcher
 	eventChan     chan Event
 	stopChan      chan struct{}
+	stopOnce      sync.Once
 	doneChan      chan struct{}
 }
 
@@ -57,7 +72,6 @@ func (es *UnlimitedEventSource) Events() <-chan Event {
 }
 
 func (es *UnlimitedEventSource) Close() {
-	// for completeness sake, but will never be called
 	_ = es.watcher.Close()
 }
 
@@ -66,12 +80,21 @@ func (es *UnlimitedEventSource) Wait() {
 	<-es.doneChan
 }
 
+// Stop requests the EventSource to stop. Safe to call multiple times and
+// before Run is started.
 func (es *UnlimitedEventSource) Stop() {
-	es.stopChan <- struct{}{}
+	es.stopOnce.Do(func() {
+		close(es.stopChan)
+	})
 }
 
-func (es *UnlimitedEventSource) Run() {
-	es.eventChan <- Event{Timestamp: time.Now()}
+// Run emits events until either the context is cancelled or Stop is called.
+func (es *UnlimitedEventSource) Run(ctx context.Context) {
+	defer close(es.doneChan)
+
+	if !es.send(ctx, Event{Timestamp: time.Now()}, TriggerInitial) {
+		return
+	}
 	klog.V(2).Infof("initial update trigger")
 
 	timeEvents := make(<-chan time.Time)
@@ -81,35 +104,52 @@ func (es *UnlimitedEventSource) Run() {
 		timeEvents = ticker.C
 	}
 
-	done := false
-	for !done {
+	for {
 		// TODO: what about closed channels?
 		select {
 		case tickTs := <-timeEvents:
-			es.eventChan <- Event{
-				Timestamp:     tickTs,
-				TimerInterval: es.sleepInterval,
+			if !es.send(ctx, Event{Timestamp: tickTs, TimerInterval: es.sleepInterval}, TriggerTimer) {
+				return
 			}
 			klog.V(4).Infof("timer update trigger")
-
 		case event := <-es.watcher.Events:
 			klog.V(5).Infof("fsnotify event from %q: %v", event.Name, event.Op)
 			if AnyFilter(es.filters, event) {
-				es.eventChan <- Event{
-					Timestamp: time.Now(),
+				if !es.send(ctx, Event{Timestamp: time.Now()}, TriggerFile) {
+					return
 				}
 				klog.V(4).Infof("fsnotify update trigger")
 			}
-
 		case err := <-es.watcher.Errors:
 			// and yes, keep going
 			klog.Warningf("fsnotify error: %v", err)
-
 		case <-es.stopChan:
-			done = true
+			return
+		case <-ctx.Done():
+			return
 		}
 	}
-	es.doneChan <- struct{}{}
+}
+
+// send delivers the event unless the EventSource is stopping. Returns false if the EventSource should stop.
+// Each event starts a new trace; its span lasts until the event is consumed.
+func (es *UnlimitedEventSource) send(ctx context.Context, ev Event, trigger string) bool {
+	_, span := tracing.Tracer().Start(context.Background(), "notification/event",
+		trace.WithNewRoot(),
+		trace.WithTimestamp(ev.Timestamp),
+		trace.WithAttributes(attribute.String(tracing.AttrTrigger, trigger)),
+	)
+	defer span.End()
+	ev.SpanContext = span.SpanContext()
+
+	select {
+	case es.eventChan <- ev:
+		return true
+	case <-es.stopChan:
+		return false
+	case <-ctx.Done():
+		return false
+	}
 }
 
 func (es *UnlimitedEventSource) SetInterval(interval time.Duration) error {
@@ -1,6 +1,7 @@
 package notification
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -179,3 +180,52 @@ func TestSetIntervalMultipleTimes(t *testing.T) {
 		t.Errorf("NOT got error setting the interval more than once")
 	}
 }
+
+func TestRunStopsOnContextCancel(t *testing.T) {
+	es, err := NewUnlimitedEventSource()
+	if err != nil {
+		t.Fatalf("error creating event source: %v", err)
+	}
+	defer es.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go es.Run(ctx)
+
+	// consume the initial trigger, then nobody reads anymore
+	<-es.Events()
+	cancel()
+
+	select {
+	case <-waitChan(es):
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event source did not stop after context cancellation")
+	}
+}
+
+func TestStopWithoutReader(t *testing.T) {
+	es, err := NewUnlimitedEventSource()
+	if err != nil {
+		t.Fatalf("error creating event source: %v", err)
+	}
+	defer es.Close()
+
+	// nobody consumes the initial trigger: Stop must not block anyway
+	go es.Run(context.Background())
+	es.Stop()
+	es.Stop()
+
+	select {
+	case <-waitChan(es):
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event source did not stop")
+	}
+}
+
+func waitChan(es EventSource) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		es.Wait()
+	}()
+	return done
+}
@@ -0,0 +1,98 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/dump"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sannotations"
+)
+
+// dryRunVolatileAnnotations change on every scan or are set by the exporter itself,
+// so they are left out of the dry-run diffs which would otherwise never be empty.
+var dryRunVolatileAnnotations = []string{
+	k8sannotations.ScanSequence,
+	k8sannotations.ScanTimestamp,
+	AnnotationStale,
+}
+
+// dryRun tracks the objects which would have been sent, and logs the differences between
+// consecutive ones, so the effect of the configuration can be evaluated without writing anything.
+// It returns the would-be object.
+func (te *NRTUpdater) dryRun(ctx context.Context, info MonitorInfo) *v1alpha2.NodeResourceTopology {
+	if !te.dryRunFetched {
+		te.dryRunFetched = true
+		te.dryRunPrev = te.fetchDryRunBaseline(ctx)
+	}
+
+	if te.dryRunPrev == nil {
+		nrtNew := te.makeNRT(ctx, info)
+		klog.InfoS("nrtupdater dry-run initial object", "node", te.args.Hostname, "trigger", info.UpdateReason(), "object", dump.Object(nrtNew))
+		te.dryRunPrev = nrtNew
+		return nrtNew
+	}
+
+	// like in the patch path, start from the previous object to compute a meaningful diff
+	nrtNew := te.dryRunPrev.DeepCopy()
+	te.updateNRTInfo(nrtNew, info)
+	te.updateNodeMetadata(ctx, nrtNew)
+	te.updateOwnerReferences(ctx, nrtNew)
+
+	patchInfo, reason, err := makeDryRunPatch(te.dryRunPrev, nrtNew)
+	if err != nil {
+		klog.InfoS("nrtupdater dry-run failed to compute the diff", "node", te.args.Hostname, "step", reason, "error", err)
+	} else if string(patchInfo.Patch) == "{}" {
+		klog.V(4).InfoS("nrtupdater dry-run no changes", "node", te.args.Hostname, "trigger", info.UpdateReason())
+	} else {
+		klog.InfoS("nrtupdater dry-run diff", "node", te.args.Hostname, "trigger", info.UpdateReason(), "patch", string(patchInfo.Patch), "sizeRatio", patchInfo.SizeRatio())
+	}
+	te.dryRunPrev = nrtNew
+	return nrtNew
+}
+
+// fetchDryRunBaseline gets the current NRT object, if the NRT API is the destination and it is reachable,
+// so the first diff is against the data actually published. Returns nil otherwise.
+func (te *NRTUpdater) fetchDryRunBaseline(ctx context.Context) *v1alpha2.NodeResourceTopology {
+	if te.publisher != nil {
+		return nil
+	}
+	nrt, err := te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Get(ctx, te.args.Hostname, metav1.GetOptions{})
+	if err != nil {
+		klog.V(2).Infof("nrtupdater dry-run: cannot get the current NRT object, starting from scratch: %v", err)
+		return nil
+	}
+	return nrt
+}
+
+// makeDryRunPatch is like MakeNRTPatch, but ignores the dryRunVolatileAnnotations.
+func makeDryRunPatch(nrtOld, nrtNew *v1alpha2.NodeResourceTopology) (NRTPatchInfo, string, error) {
+	return MakeNRTPatch(withoutVolatileAnnotations(nrtOld), withoutVolatileAnnotations(nrtNew))
+}
+
+func withoutVolatileAnnotations(nrt *v1alpha2.NodeResourceTopology) *v1alpha2.NodeResourceTopology {
+	ret := nrt.DeepCopy()
+	for _, key := range dryRunVolatileAnnotations {
+		delete(ret.Annotations, key)
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/dump"
+)
+
+const (
+	FileFormatJSON = "json"
+	FileFormatYAML = "yaml"
+)
+
+// FilePublisherStdout is the special file path which selects the standard output.
+const FilePublisherStdout = "-"
+
+func FileFormatSupported() string {
+	formats := []string{
+		FileFormatJSON,
+		FileFormatYAML,
+	}
+	return strings.Join(formats, ",")
+}
+
+func FileFormatIsSupported(value string) (string, error) {
+	val := strings.ToLower(value)
+	switch val {
+	case "", FileFormatJSON, FileFormatYAML:
+		return val, nil
+	default:
+		return val, fmt.Errorf("unsupported file format %q", value)
+	}
+}
+
+// FilePublisher writes the NRT objects to a local file or to the standard output.
+// By default the file is atomically replaced with the latest object. In history mode,
+// the objects are appended instead, one JSON document per line.
+type FilePublisher struct {
+	path    string
+	format  string
+	history bool
+	stdout  io.Writer
+}
+
+func NewFilePublisher(args Args) (*FilePublisher, error) {
+	format, err := FileFormatIsSupported(args.PublisherFormat)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = FileFormatJSON
+	}
+	if args.PublisherHistory && format != FileFormatJSON {
+		return nil, fmt.Errorf("history is supported only with the %s format", FileFormatJSON)
+	}
+	path := args.PublisherFile
+	if path == "" {
+		path = FilePublisherStdout
+	}
+	return &FilePublisher{
+		path:    path,
+		format:  format,
+		history: args.PublisherHistory,
+		stdout:  os.Stdout,
+	}, nil
+}
+
+func (pub *FilePublisher) Name() string {
+	return PublisherFile
+}
+
+func (pub *FilePublisher) Publish(_ context.Context, nrt *v1alpha2.NodeResourceTopology) error {
+	data, err := pub.marshal(nrt)
+	if err != nil {
+		return err
+	}
+	if pub.path == FilePublisherStdout {
+		if pub.format == FileFormatYAML {
+			data = append([]byte("---\n"), data...)
+		}
+		_, err = pub.stdout.Write(data)
+		return err
+	}
+	if pub.history {
+		return appendToFile(pub.path, data)
+	}
+	dir, file := filepath.Split(pub.path)
+	return dump.ReplaceFile(dir, file, data, 0644)
+}
+
+func (pub *FilePublisher) marshal(nrt *v1alpha2.NodeResourceTopology) ([]byte, error) {
+	if pub.format == FileFormatYAML {
+		return yaml.Marshal(nrt)
+	}
+	data, err := json.Marshal(nrt)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func appendToFile(path string, data []byte) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(data)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
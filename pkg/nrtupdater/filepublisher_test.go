@@ -0,0 +1,183 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+func TestNewFilePublisher(t *testing.T) {
+	type testCase struct {
+		name          string
+		args          Args
+		expectedError bool
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name: "defaults",
+		},
+		{
+			name: "yaml",
+			args: Args{PublisherFormat: "YAML"},
+		},
+		{
+			name: "json history",
+			args: Args{PublisherHistory: true},
+		},
+		{
+			name:          "yaml history",
+			args:          Args{PublisherFormat: FileFormatYAML, PublisherHistory: true},
+			expectedError: true,
+		},
+		{
+			name:          "unsupported format",
+			args:          Args{PublisherFormat: "toml"},
+			expectedError: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := NewFilePublisher(tcase.args)
+			gotErr := (err != nil)
+			if gotErr != tcase.expectedError {
+				t.Errorf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+		})
+	}
+}
+
+func TestFilePublisherReplace(t *testing.T) {
+	for _, format := range []string{FileFormatJSON, FileFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nrt."+format)
+			nrtUpd := newFilePublisherUpdater(t, Args{PublisherFile: path, PublisherFormat: format})
+
+			for _, zoneName := range []string{"zone-0", "zone-1"} {
+				err := nrtUpd.Update(context.Background(), MonitorInfo{Zones: v1alpha2.ZoneList{{Name: zoneName, Type: "node"}}})
+				if err != nil {
+					t.Fatalf("update failed: %v", err)
+				}
+
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("failed to read %q: %v", path, err)
+				}
+				var nrt v1alpha2.NodeResourceTopology
+				err = yaml.Unmarshal(data, &nrt) // works with json too
+				if err != nil {
+					t.Fatalf("failed to decode %q: %v", path, err)
+				}
+				checkPublishedNRT(t, &nrt, zoneName)
+			}
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			if err != nil {
+				t.Fatalf("failed to read the output directory: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("leftover temporary files: %v", entries)
+			}
+		})
+	}
+}
+
+func TestFilePublisherHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nrt.jsonl")
+	nrtUpd := newFilePublisherUpdater(t, Args{PublisherFile: path, PublisherHistory: true})
+
+	zoneNames := []string{"zone-0", "zone-1", "zone-2"}
+	for _, zoneName := range zoneNames {
+		err := nrtUpd.Update(context.Background(), MonitorInfo{Zones: v1alpha2.ZoneList{{Name: zoneName, Type: "node"}}})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %q: %v", path, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(zoneNames) {
+		t.Fatalf("unexpected history length: got %d expected %d", len(lines), len(zoneNames))
+	}
+	for idx, line := range lines {
+		var nrt v1alpha2.NodeResourceTopology
+		err = json.Unmarshal([]byte(line), &nrt)
+		if err != nil {
+			t.Fatalf("failed to decode line %d: %v", idx, err)
+		}
+		checkPublishedNRT(t, &nrt, zoneNames[idx])
+	}
+}
+
+func TestFilePublisherStdout(t *testing.T) {
+	pub, err := NewFilePublisher(Args{PublisherFormat: FileFormatYAML})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	var buf bytes.Buffer
+	pub.stdout = &buf
+
+	nrt := v1alpha2.NodeResourceTopology{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}}
+	nrt.Name = "test-node"
+	err = pub.Publish(context.Background(), &nrt)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "---\n") || !strings.Contains(out, "name: zone-0") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func newFilePublisherUpdater(t *testing.T, args Args) *NRTUpdater {
+	t.Helper()
+	args.Hostname = "test-node"
+	pubArgs := args
+	pubArgs.Publisher = PublisherFile
+	pub, err := NewPublisher(pubArgs, nil)
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	// the file publisher must work without API access
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, nil, args, TMConfig{}, WithPublisher(pub))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+	return nrtUpd
+}
+
+func checkPublishedNRT(t *testing.T, nrt *v1alpha2.NodeResourceTopology, zoneName string) {
+	t.Helper()
+	if nrt.Kind != "NodeResourceTopology" || nrt.Name != "test-node" {
+		t.Errorf("unexpected object identity: %v/%v", nrt.Kind, nrt.Name)
+	}
+	if len(nrt.Zones) != 1 || nrt.Zones[0].Name != zoneName {
+		t.Errorf("unexpected zones: %v", nrt.Zones)
+	}
+}
@@ -4,10 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
 )
 
 var NotConfigured = errors.New("unconfigured feature")
@@ -42,28 +51,127 @@ func (ng *DisabledNodeGetter) Get(ctx context.Context, nodeName string, opts met
 	return nil, fmt.Errorf("%w", NotConfigured)
 }
 
-type CachedNodeGetter struct {
-	nodes map[string]*corev1.Node
+// NodeChangeNotifier is implemented by the NodeGetters which can detect the Node being re-created,
+// hence the owner references pointing to it needing to be refreshed.
+type NodeChangeNotifier interface {
+	NodeChanges() <-chan struct{}
+}
+
+// NodeMetadataNotifier is implemented by the NodeGetters which can detect the Node labels or annotations
+// matching the given patterns changing, hence the metadata mirrored onto the NRT needing to be refreshed.
+type NodeMetadataNotifier interface {
+	NodeMetadataChanges(labelPatterns, annotationPatterns []string) <-chan struct{}
 }
 
-func NewCachedNodeGetter(k8sInterface kubernetes.Interface, ctx context.Context) (*CachedNodeGetter, error) {
-	nodelist, err := k8sInterface.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+// InformerNodeGetter watches only the given Node, so it is cheap regardless of the cluster size
+// and keeps the Node data, most notably the UID, current if the Node is re-created.
+type InformerNodeGetter struct {
+	nodeName   string
+	lister     corelisters.NodeLister
+	changeChan chan struct{}
+	metaChan   chan struct{}
+	lock       sync.Mutex
+	lastUID    types.UID
+	// patterns of the labels and annotations whose changes are notified, and their last seen values
+	labelPatterns      []string
+	annotationPatterns []string
+	lastLabels         map[string]string
+	lastAnnotations    map[string]string
+}
+
+// NewInformerNodeGetter builds a NodeGetter from a node-scoped informer factory,
+// see k8shelpers.NewNodeScopedInformerFactory.
+func NewInformerNodeGetter(ctx context.Context, factory informers.SharedInformerFactory, nodeName string) (*InformerNodeGetter, error) {
+	nodeInformer := factory.Core().V1().Nodes()
+
+	ng := &InformerNodeGetter{
+		nodeName:   nodeName,
+		lister:     nodeInformer.Lister(),
+		changeChan: make(chan struct{}, 1),
+		metaChan:   make(chan struct{}, 1),
+	}
+	_, err := nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ng.nodeSeen(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			ng.nodeSeen(obj)
+		},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to get node list information: %w", err)
+		return nil, fmt.Errorf("unable to watch node %q: %w", nodeName, err)
 	}
 
-	retVal := &CachedNodeGetter{nodes: make(map[string]*corev1.Node, len(nodelist.Items))}
-	for idx := range nodelist.Items {
-		node := &nodelist.Items[idx]
-		retVal.nodes[node.Name] = node
+	err = k8shelpers.StartAndSync(ctx, factory)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get node information: %w", err)
 	}
+	return ng, nil
+}
 
-	return retVal, nil
+func (ng *InformerNodeGetter) Get(ctx context.Context, nodeName string, _ metav1.GetOptions) (*corev1.Node, error) {
+	if nodeName != ng.nodeName {
+		return nil, fmt.Errorf("%w", NotFound{NodeName: nodeName})
+	}
+	node, err := ng.lister.Get(nodeName)
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w", NotFound{NodeName: nodeName})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// NodeChanges returns a channel which receives a notification every time the watched Node is re-created.
+func (ng *InformerNodeGetter) NodeChanges() <-chan struct{} {
+	return ng.changeChan
+}
+
+// NodeMetadataChanges returns a channel which receives a notification every time the watched Node labels
+// or annotations matching the given patterns change. The patterns replace the ones previously given, if any.
+func (ng *InformerNodeGetter) NodeMetadataChanges(labelPatterns, annotationPatterns []string) <-chan struct{} {
+	ng.lock.Lock()
+	defer ng.lock.Unlock()
+	ng.labelPatterns = labelPatterns
+	ng.annotationPatterns = annotationPatterns
+	ng.lastLabels, ng.lastAnnotations = nil, nil
+	if node, err := ng.lister.Get(ng.nodeName); err == nil {
+		ng.lastLabels = mirrorKeys(nil, node.Labels, labelPatterns)
+		ng.lastAnnotations = mirrorKeys(nil, node.Annotations, annotationPatterns)
+	}
+	return ng.metaChan
+}
+
+// nodeSeen tracks the Node UID and the watched labels and annotations, and notifies their changes.
+func (ng *InformerNodeGetter) nodeSeen(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+
+	ng.lock.Lock()
+	prevUID := ng.lastUID
+	ng.lastUID = node.UID
+	prevLabels, prevAnnotations := ng.lastLabels, ng.lastAnnotations
+	ng.lastLabels = mirrorKeys(nil, node.Labels, ng.labelPatterns)
+	ng.lastAnnotations = mirrorKeys(nil, node.Annotations, ng.annotationPatterns)
+	metaChanged := !maps.Equal(prevLabels, ng.lastLabels) || !maps.Equal(prevAnnotations, ng.lastAnnotations)
+	ng.lock.Unlock()
+
+	if prevUID != "" && prevUID != node.UID {
+		klog.Infof("nrtupdater: node %q re-created: UID %q -> %q", node.Name, prevUID, node.UID)
+		notify(ng.changeChan)
+	}
+	if metaChanged {
+		klog.V(4).Infof("nrtupdater: node %q mirrored labels or annotations changed", node.Name)
+		notify(ng.metaChan)
+	}
 }
 
-func (ng *CachedNodeGetter) Get(ctx context.Context, nodeName string, _ metav1.GetOptions) (*corev1.Node, error) {
-	if node, found := ng.nodes[nodeName]; found {
-		return node, nil
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default: // a notification is already pending, nothing to do
 	}
-	return nil, fmt.Errorf("%w", NotFound{NodeName: nodeName})
 }
@@ -0,0 +1,185 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientk8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
+)
+
+func TestInformerNodeGetterGet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodeName := "test-node"
+	k8sClient := clientk8sfake.NewSimpleClientset(makeNode(nodeName, "uid-1"))
+	ng, err := NewInformerNodeGetter(ctx, k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
+	if err != nil {
+		t.Fatalf("failed to create node getter: %v", err)
+	}
+
+	node, err := ng.Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node %q: %v", nodeName, err)
+	}
+	if node.UID != "uid-1" {
+		t.Errorf("unexpected UID: %q", node.UID)
+	}
+
+	_, err = ng.Get(ctx, "other-node", metav1.GetOptions{})
+	var nf NotFound
+	if !errors.As(err, &nf) {
+		t.Errorf("expected NotFound for a different node, got %v", err)
+	}
+}
+
+func TestInformerNodeGetterNodeRecreated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodeName := "test-node"
+	k8sClient := clientk8sfake.NewSimpleClientset(makeNode(nodeName, "uid-1"))
+	ng, err := NewInformerNodeGetter(ctx, k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
+	if err != nil {
+		t.Fatalf("failed to create node getter: %v", err)
+	}
+
+	cli := fake.NewSimpleClientset()
+	nrtUpd, err := NewNRTUpdater(ng, cli, Args{Hostname: nodeName}, TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+	err = nrtUpd.Update(ctx, MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}})
+	if err != nil {
+		t.Fatalf("failed to perform the initial creation: %v", err)
+	}
+	checkNRTOwnerUID(t, cli, nodeName, "uid-1")
+
+	err = k8sClient.CoreV1().Nodes().Delete(ctx, nodeName, metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("failed to delete node: %v", err)
+	}
+	_, err = k8sClient.CoreV1().Nodes().Create(ctx, makeNode(nodeName, "uid-2"), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed to re-create node: %v", err)
+	}
+
+	select {
+	case <-ng.NodeChanges():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("node re-creation not detected")
+	}
+
+	err = nrtUpd.RefreshOwnerReferences(ctx)
+	if err != nil {
+		t.Fatalf("failed to refresh owner references: %v", err)
+	}
+	checkNRTOwnerUID(t, cli, nodeName, "uid-2")
+}
+
+func TestInformerNodeGetterNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodeName := "test-node"
+	node := makeNode(nodeName, "uid-1")
+	node.Labels = map[string]string{"pool.example.com/name": "pool-1"}
+	k8sClient := clientk8sfake.NewSimpleClientset(node)
+	ng, err := NewInformerNodeGetter(ctx, k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
+	if err != nil {
+		t.Fatalf("failed to create node getter: %v", err)
+	}
+	metaChan := ng.NodeMetadataChanges([]string{"pool.example.com/*"}, nil)
+
+	updateNode := func(mutate func(*corev1.Node)) {
+		t.Helper()
+		cur, err := k8sClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get node: %v", err)
+		}
+		mutate(cur)
+		_, err = k8sClient.CoreV1().Nodes().Update(ctx, cur, metav1.UpdateOptions{})
+		if err != nil {
+			t.Fatalf("failed to update node: %v", err)
+		}
+	}
+	expectNotification := func(ch <-chan struct{}, expected bool, what string) {
+		t.Helper()
+		timeout := 5 * time.Second
+		if !expected {
+			timeout = 200 * time.Millisecond
+		}
+		select {
+		case <-ch:
+			if !expected {
+				t.Errorf("unexpected notification: %s", what)
+			}
+		case <-time.After(timeout):
+			if expected {
+				t.Errorf("missing notification: %s", what)
+			}
+		}
+	}
+
+	updateNode(func(node *corev1.Node) {
+		node.Labels["kubernetes.io/hostname"] = nodeName
+		node.Annotations = map[string]string{"node.alpha.kubernetes.io/ttl": "0"}
+	})
+	expectNotification(metaChan, false, "metadata change not matching the patterns")
+	expectNotification(ng.NodeChanges(), false, "owner change on metadata change")
+
+	updateNode(func(node *corev1.Node) {
+		node.Labels["pool.example.com/name"] = "pool-2"
+	})
+	expectNotification(metaChan, true, "metadata change matching the patterns")
+	expectNotification(ng.NodeChanges(), false, "owner change on metadata change")
+}
+
+func makeNode(name string, uid types.UID) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  uid,
+		},
+	}
+}
+
+func checkNRTOwnerUID(t *testing.T, cli *fake.Clientset, name string, expected types.UID) {
+	t.Helper()
+	nrt, err := cli.TopologyV1alpha2().NodeResourceTopologies().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get NRT %q: %v", name, err)
+	}
+	if len(nrt.OwnerReferences) != 1 {
+		t.Fatalf("unexpected owner references: %v", nrt.OwnerReferences)
+	}
+	if nrt.OwnerReferences[0].UID != expected {
+		t.Errorf("unexpected owner UID: got %q expected %q", nrt.OwnerReferences[0].UID, expected)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+// reservedKeyPrefix is the prefix of the labels and annotations owned by the exporter itself,
+// which are never mirrored from the Node, nor removed from the NRT, regardless of the patterns.
+const reservedKeyPrefix = "k8stopoawareschedwg/"
+
+// ParseMirrorPatterns splits a comma-separated list of glob patterns (path.Match syntax)
+// and validates them.
+func ParseMirrorPatterns(value string) ([]string, error) {
+	var patterns []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, err := path.Match(item, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", item, err)
+		}
+		patterns = append(patterns, item)
+	}
+	return patterns, nil
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mirrorKeys makes dst reflect the keys of src matching the patterns: the matching keys
+// are copied over, and the matching keys in dst missing in src are removed.
+// Returns the (possibly newly allocated) dst.
+func mirrorKeys(dst, src map[string]string, patterns []string) map[string]string {
+	if len(patterns) == 0 {
+		return dst
+	}
+	for key := range dst {
+		if strings.HasPrefix(key, reservedKeyPrefix) || !matchAny(patterns, key) {
+			continue
+		}
+		if _, ok := src[key]; !ok {
+			delete(dst, key)
+		}
+	}
+	for key, value := range src {
+		if strings.HasPrefix(key, reservedKeyPrefix) || !matchAny(patterns, key) {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[string]string)
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// WithNodeMetadataGetter sets the source of the Node labels and annotations to mirror, which defaults
+// to the NodeGetter used for the owner references, so mirroring can be enabled without them.
+// A nil NodeGetter is ignored.
+func WithNodeMetadataGetter(ng NodeGetter) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		if ng == nil {
+			return
+		}
+		te.metaGetter = ng
+	}
+}
+
+// watchNodeMetadata subscribes to the changes of the mirrored Node metadata, if the source supports it.
+func (te *NRTUpdater) watchNodeMetadata() {
+	if notifier, ok := te.metaGetter.(NodeMetadataNotifier); ok && te.mirrorsNodeMetadata() {
+		te.metaChan = notifier.NodeMetadataChanges(te.labelPatterns, te.annotationPatterns)
+	}
+}
+
+func (te *NRTUpdater) mirrorsNodeMetadata() bool {
+	return len(te.labelPatterns) > 0 || len(te.annotationPatterns) > 0
+}
+
+// updateNodeMetadata mirrors the Node labels and annotations selected by the configured patterns onto the NRT.
+func (te *NRTUpdater) updateNodeMetadata(ctx context.Context, nrt *v1alpha2.NodeResourceTopology) {
+	if !te.mirrorsNodeMetadata() {
+		return
+	}
+	node, err := te.metaGetter.Get(ctx, nrt.Name, metav1.GetOptions{})
+	if err != nil {
+		klog.V(7).Infof("nrtupdater unable to get Node %s. Can't mirror labels and annotations. error: %v", nrt.Name, err)
+		return
+	}
+	nrt.Labels = mirrorKeys(nrt.Labels, node.Labels, te.labelPatterns)
+	nrt.Annotations = mirrorKeys(nrt.Annotations, node.Annotations, te.annotationPatterns)
+}
+
+// RefreshNodeMetadata patches the NRT object to mirror the current Node labels and annotations
+// selected by the configured patterns. Does nothing if the NRT is already in sync, or if the data
+// is sent through a Publisher, because the next update will rewrite it anyway.
+func (te *NRTUpdater) RefreshNodeMetadata(ctx context.Context) error {
+	if te.args.NoPublish || te.publisher != nil || !te.mirrorsNodeMetadata() {
+		return nil
+	}
+	nrtOld := te.prevNRT
+	if nrtOld == nil {
+		var err error
+		nrtOld, err = te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Get(ctx, te.args.Hostname, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// nothing to do, the next update will create the object with the correct metadata
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	nrtNew := nrtOld.DeepCopy()
+	te.updateNodeMetadata(ctx, nrtNew)
+
+	patchInfo, _, err := MakeNRTPatch(nrtOld, nrtNew)
+	if err != nil {
+		return err
+	}
+	if string(patchInfo.Patch) == "{}" {
+		return nil
+	}
+	nrtUpdated, err := te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Patch(ctx, nrtOld.Name, types.MergePatchType, patchInfo.Patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	if te.prevNRT != nil {
+		te.prevNRT = nrtUpdated
+	}
+	klog.V(2).Infof("nrtupdater refreshed node metadata: %s", string(patchInfo.Patch))
+	return nil
+}
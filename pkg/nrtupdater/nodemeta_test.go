@@ -0,0 +1,237 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientk8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
+)
+
+func TestParseMirrorPatterns(t *testing.T) {
+	type testCase struct {
+		name          string
+		value         string
+		expected      []string
+		expectedError bool
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:  "empty",
+			value: "",
+		},
+		{
+			name:     "multiple with spaces",
+			value:    "topology.kubernetes.io/*, node.kubernetes.io/instance-type,,",
+			expected: []string{"topology.kubernetes.io/*", "node.kubernetes.io/instance-type"},
+		},
+		{
+			name:          "malformed",
+			value:         "pool/[a-",
+			expectedError: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := ParseMirrorPatterns(tcase.value)
+			gotErr := (err != nil)
+			if gotErr != tcase.expectedError {
+				t.Fatalf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+			if !reflect.DeepEqual(got, tcase.expected) {
+				t.Errorf("patterns mismatch: got %v expected %v", got, tcase.expected)
+			}
+		})
+	}
+}
+
+func TestMirrorKeys(t *testing.T) {
+	type testCase struct {
+		name     string
+		dst      map[string]string
+		src      map[string]string
+		patterns []string
+		expected map[string]string
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:     "no patterns",
+			dst:      map[string]string{"pool": "a"},
+			src:      map[string]string{"zone": "z1"},
+			expected: map[string]string{"pool": "a"},
+		},
+		{
+			name:     "copy into nil",
+			src:      map[string]string{"topology.kubernetes.io/zone": "z1", "other": "x"},
+			patterns: []string{"topology.kubernetes.io/*"},
+			expected: map[string]string{"topology.kubernetes.io/zone": "z1"},
+		},
+		{
+			name:     "update and remove",
+			dst:      map[string]string{"pool/a": "old", "pool/b": "gone", "unrelated": "keep"},
+			src:      map[string]string{"pool/a": "new"},
+			patterns: []string{"pool/*"},
+			expected: map[string]string{"pool/a": "new", "unrelated": "keep"},
+		},
+		{
+			name:     "reserved keys untouched",
+			dst:      map[string]string{LabelNodeIdentity: "node-a"},
+			src:      map[string]string{LabelNodeIdentity: "node-b", AnnotationStale: "true"},
+			patterns: []string{"*"},
+			expected: map[string]string{LabelNodeIdentity: "node-a"},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := mirrorKeys(tcase.dst, tcase.src, tcase.patterns)
+			if !reflect.DeepEqual(got, tcase.expected) {
+				t.Errorf("mirror mismatch: got %v expected %v", got, tcase.expected)
+			}
+		})
+	}
+}
+
+func TestNodeMetadataMirrored(t *testing.T) {
+	for _, mode := range updateModes {
+		t.Run(mode.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			nodeName := "test-node"
+			node := makeNode(nodeName, "uid-1")
+			node.Labels = map[string]string{
+				"topology.kubernetes.io/zone": "zone-a",
+				"kubernetes.io/hostname":      nodeName,
+			}
+			node.Annotations = map[string]string{
+				"pool.example.com/name": "pool-1",
+			}
+			k8sClient := clientk8sfake.NewSimpleClientset(node)
+			ng, err := NewInformerNodeGetter(ctx, k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
+			if err != nil {
+				t.Fatalf("failed to create node getter: %v", err)
+			}
+
+			cli := fake.NewSimpleClientset()
+			args := Args{
+				Hostname:        nodeName,
+				PatchMode:       mode.patchMode,
+				NodeLabels:      "topology.kubernetes.io/*",
+				NodeAnnotations: "pool.example.com/*",
+			}
+			nrtUpd, err := NewNRTUpdater(ng, cli, args, TMConfig{})
+			if err != nil {
+				t.Fatalf("failed to create NRT updater: %v", err)
+			}
+			for range 2 { // make sure we exercise the patch path, if enabled
+				err = nrtUpd.Update(ctx, MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}})
+				if err != nil {
+					t.Fatalf("update failed: %v", err)
+				}
+			}
+			checkNRTMetadata(t, cli, nodeName, "topology.kubernetes.io/zone", "zone-a", "pool.example.com/name", "pool-1")
+
+			nodeUpdated := node.DeepCopy()
+			nodeUpdated.Labels["topology.kubernetes.io/zone"] = "zone-b"
+			nodeUpdated.Annotations = nil
+			_, err = k8sClient.CoreV1().Nodes().Update(ctx, nodeUpdated, metav1.UpdateOptions{})
+			if err != nil {
+				t.Fatalf("failed to update node: %v", err)
+			}
+
+			select {
+			case <-nrtUpd.metaChan:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("node metadata change not detected")
+			}
+
+			err = nrtUpd.RefreshNodeMetadata(ctx)
+			if err != nil {
+				t.Fatalf("failed to refresh node metadata: %v", err)
+			}
+			checkNRTMetadata(t, cli, nodeName, "topology.kubernetes.io/zone", "zone-b", "pool.example.com/name", "")
+		})
+	}
+}
+
+func TestNodeMetadataMirroredWithoutOwnerReferences(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodeName := "test-node"
+	node := makeNode(nodeName, "uid-1")
+	node.Labels = map[string]string{"topology.kubernetes.io/zone": "zone-a"}
+	node.Annotations = map[string]string{"pool.example.com/name": "pool-1"}
+	k8sClient := clientk8sfake.NewSimpleClientset(node)
+	ng, err := NewInformerNodeGetter(ctx, k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
+	if err != nil {
+		t.Fatalf("failed to create node getter: %v", err)
+	}
+
+	cli := fake.NewSimpleClientset()
+	args := Args{
+		Hostname:        nodeName,
+		NodeLabels:      "topology.kubernetes.io/*",
+		NodeAnnotations: "pool.example.com/*",
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, args, TMConfig{}, WithNodeMetadataGetter(ng))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	err = nrtUpd.Update(ctx, MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	checkNRTMetadata(t, cli, nodeName, "topology.kubernetes.io/zone", "zone-a", "pool.example.com/name", "pool-1")
+	nrt, err := cli.TopologyV1alpha2().NodeResourceTopologies().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get NRT %q: %v", nodeName, err)
+	}
+	if len(nrt.OwnerReferences) != 0 {
+		t.Errorf("unexpected owner references: %v", nrt.OwnerReferences)
+	}
+}
+
+func checkNRTMetadata(t *testing.T, cli *fake.Clientset, name, labelKey, labelValue, annKey, annValue string) {
+	t.Helper()
+	nrt, err := cli.TopologyV1alpha2().NodeResourceTopologies().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get NRT %q: %v", name, err)
+	}
+	if got := nrt.Labels[labelKey]; got != labelValue {
+		t.Errorf("unexpected label %q: got %q expected %q", labelKey, got, labelValue)
+	}
+	if _, ok := nrt.Labels["kubernetes.io/hostname"]; ok {
+		t.Errorf("unexpected label not matching the patterns: %v", nrt.Labels)
+	}
+	if nrt.Labels[LabelNodeIdentity] != NodeIdentity(name) {
+		t.Errorf("node identity label lost: %v", nrt.Labels)
+	}
+	if got := nrt.Annotations[annKey]; got != annValue {
+		t.Errorf("unexpected annotation %q: got %q expected %q", annKey, got, annValue)
+	}
+}
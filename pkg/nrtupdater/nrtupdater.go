@@ -2,25 +2,40 @@ package nrtupdater
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
 	"k8s.io/klog/v2"
 
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
+	"github.com/k8stopologyawareschedwg/podfingerprint"
 
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/audit"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/debugstate"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/dump"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/health"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sannotations"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sevents"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podreadiness"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/tracing"
 )
 
 const (
@@ -28,6 +43,32 @@ const (
 	RTEUpdateReactive = "reactive"
 )
 
+const (
+	// LabelNodeIdentity marks the NRT objects written by the exporter running on the given node.
+	// The value is NodeIdentity of the kubernetes node name, which does not change if the NRT name is overridden.
+	LabelNodeIdentity = "k8stopoawareschedwg/rte-node"
+	// AnnotationNodeName holds the full kubernetes node name, which may not fit in the LabelNodeIdentity value.
+	AnnotationNodeName = "k8stopoawareschedwg/rte-node-name"
+	// AnnotationStale marks the NRT objects found stale by the exporter and left in place.
+	AnnotationStale = "k8stopoawareschedwg/rte-stale"
+)
+
+const (
+	StaleCleanupDisabled = "disabled"
+	StaleCleanupDelete   = "delete"
+	StaleCleanupMark     = "mark"
+)
+
+// NodeIdentity returns the LabelNodeIdentity value for the given node name. Node names can be up to 253
+// characters long, while label values are limited to 63, so the value is a hash of the name.
+func NodeIdentity(nodeName string) string {
+	sum := sha256.Sum256([]byte(nodeName))
+	return hex.EncodeToString(sum[:16])
+}
+
+// publishFailuresThreshold is the number of consecutive publish failures to be reported as an event.
+const publishFailuresThreshold = 3
+
 var (
 	ErrMissingPreviousNRT = errors.New("missing previous NRT data")
 )
@@ -40,15 +81,85 @@ type Args struct {
 	KubeConfig  string `json:"kubeConfig,omitempty"`
 	PatchMode   bool   `json:"patchMode,omitempty"`
 	PatchResync int    `json:"patchResync,omitempty"`
+	// StaleCleanup selects what to do at startup with the NRT objects previously written
+	// by the exporter on this node under a different name. Empty means disabled.
+	StaleCleanup string `json:"staleCleanup,omitempty"`
+	// NodeLabels and NodeAnnotations are comma-separated lists of glob patterns
+	// selecting the Node labels and annotations to mirror onto the NRT object.
+	NodeLabels      string `json:"nodeLabels,omitempty"`
+	NodeAnnotations string `json:"nodeAnnotations,omitempty"`
+	// Publisher selects where to send the topology data. Empty means the NRT API.
+	Publisher string `json:"publisher,omitempty"`
+	// PublisherNamespace is the namespace of the objects written by the namespaced publishers.
+	PublisherNamespace string `json:"publisherNamespace,omitempty"`
+	// PublisherFile is the destination of the file publisher, "-" or empty means the standard output.
+	PublisherFile string `json:"publisherFile,omitempty"`
+	// PublisherFormat is the serialization format of the file publisher. Empty means JSON.
+	PublisherFormat string `json:"publisherFormat,omitempty"`
+	// PublisherHistory makes the file publisher append JSON lines instead of replacing the content.
+	PublisherHistory bool `json:"publisherHistory,omitempty"`
+	// WebhookURL is the endpoint of the webhook publisher. If set and the webhook is not
+	// the selected publisher, the webhook receives the updates alongside it.
+	WebhookURL           string        `json:"webhookURL,omitempty"`
+	WebhookPayload       string        `json:"webhookPayload,omitempty"`
+	WebhookCAFile        string        `json:"webhookCAFile,omitempty"`
+	WebhookCertFile      string        `json:"webhookCertFile,omitempty"`
+	WebhookKeyFile       string        `json:"webhookKeyFile,omitempty"`
+	WebhookRetries       int           `json:"webhookRetries,omitempty"`
+	WebhookBatchSize     int           `json:"webhookBatchSize,omitempty"`
+	WebhookBatchInterval time.Duration `json:"webhookBatchInterval,omitempty"`
+	// AuditLogFile, if set, enables the JSON-lines audit log of the publishes.
+	// Non-positive limits select the defaults.
+	AuditLogFile       string `json:"auditLogFile,omitempty"`
+	AuditLogMaxSizeMB  int    `json:"auditLogMaxSizeMB,omitempty"`
+	AuditLogMaxBackups int    `json:"auditLogMaxBackups,omitempty"`
 }
 
 func (args Args) Clone() Args {
 	return Args{
-		NoPublish:   args.NoPublish,
-		Oneshot:     args.Oneshot,
-		Hostname:    args.Hostname,
-		PatchMode:   args.PatchMode,
-		PatchResync: args.PatchResync,
+		NoPublish:            args.NoPublish,
+		Oneshot:              args.Oneshot,
+		Hostname:             args.Hostname,
+		PatchMode:            args.PatchMode,
+		PatchResync:          args.PatchResync,
+		StaleCleanup:         args.StaleCleanup,
+		NodeLabels:           args.NodeLabels,
+		NodeAnnotations:      args.NodeAnnotations,
+		Publisher:            args.Publisher,
+		PublisherNamespace:   args.PublisherNamespace,
+		PublisherFile:        args.PublisherFile,
+		PublisherFormat:      args.PublisherFormat,
+		PublisherHistory:     args.PublisherHistory,
+		WebhookURL:           args.WebhookURL,
+		WebhookPayload:       args.WebhookPayload,
+		WebhookCAFile:        args.WebhookCAFile,
+		WebhookCertFile:      args.WebhookCertFile,
+		WebhookKeyFile:       args.WebhookKeyFile,
+		WebhookRetries:       args.WebhookRetries,
+		WebhookBatchSize:     args.WebhookBatchSize,
+		WebhookBatchInterval: args.WebhookBatchInterval,
+		AuditLogFile:         args.AuditLogFile,
+		AuditLogMaxSizeMB:    args.AuditLogMaxSizeMB,
+		AuditLogMaxBackups:   args.AuditLogMaxBackups,
+	}
+}
+
+func StaleCleanupSupported() string {
+	modes := []string{
+		StaleCleanupDisabled,
+		StaleCleanupDelete,
+		StaleCleanupMark,
+	}
+	return strings.Join(modes, ",")
+}
+
+func StaleCleanupIsSupported(value string) (string, error) {
+	val := strings.ToLower(value)
+	switch val {
+	case "", StaleCleanupDisabled, StaleCleanupDelete, StaleCleanupMark:
+		return val, nil
+	default:
+		return val, fmt.Errorf("unsupported stale cleanup mode %q", value)
 	}
 }
 
@@ -65,11 +176,46 @@ type NRTUpdater struct {
 	args       Args
 	tmConfig   TMConfig
 	stopChan   chan struct{}
+	stopOnce   sync.Once
 	nodeGetter NodeGetter
+	nodeChan   <-chan struct{}
+	// metaGetter provides the Node labels and annotations to mirror, metaChan notifies their changes
+	metaGetter NodeGetter
+	metaChan   <-chan struct{}
 	nrtCli     topologyclientset.Interface
 	sendObject func(context.Context, topologyclientset.Interface, MonitorInfo) (*v1alpha2.NodeResourceTopology, error)
 	prevNRT    *v1alpha2.NodeResourceTopology
 	patchCount int
+	nodeIdent  string
+	// patterns of the Node labels and annotations to mirror onto the NRT
+	labelPatterns      []string
+	annotationPatterns []string
+	// publisher, if set, replaces the NRT API as destination of the data
+	publisher Publisher
+	// mirror, if set, receives the data successfully sent to the main destination
+	mirror Publisher
+	// publishOutcomes receives the outcome of the deliveries deferred by the publisher, if it is asynchronous
+	publishOutcomes <-chan error
+	// mirrorOutcomes is like publishOutcomes, for the mirror publisher
+	mirrorOutcomes <-chan error
+	// publishPending is set if the publisher deferred the delivery of the last update, whose sequence is pendingSequence
+	publishPending  bool
+	pendingSequence uint64
+	// the last would-be object in NoPublish mode
+	dryRunPrev    *v1alpha2.NodeResourceTopology
+	dryRunFetched bool
+	auditLog      *audit.Log
+	heartbeat     Heartbeat
+	events        k8sevents.Reporter
+	failureCount  int
+	metrics       *metrics.Metrics
+	health        *health.Tracker
+	debug         *debugstate.State
+}
+
+// Heartbeat is notified after each successful publish.
+type Heartbeat interface {
+	Beat(ctx context.Context) error
 }
 
 type MonitorInfo struct {
@@ -77,6 +223,12 @@ type MonitorInfo struct {
 	Zones       v1alpha2.ZoneList
 	Attributes  v1alpha2.AttributeList
 	Annotations map[string]string
+	// Sequence is incremented on each scan attempt, so gaps reveal the skipped updates. Zero means unknown.
+	Sequence uint64
+	// ScanTime is when the scan completed. Zero means unknown.
+	ScanTime time.Time
+	// SpanContext identifies the scan span, so the writes join the trace of the update cycle
+	SpanContext trace.SpanContext
 }
 
 func (mi MonitorInfo) UpdateReason() string {
@@ -86,17 +238,46 @@ func (mi MonitorInfo) UpdateReason() string {
 	return RTEUpdateReactive
 }
 
-func NewNRTUpdater(nodeGetter NodeGetter, nrtCli topologyclientset.Interface, args Args, tmconf TMConfig) (*NRTUpdater, error) {
-	if nrtCli == nil {
-		return nil, fmt.Errorf("missing NRT client interface")
-	}
+func NewNRTUpdater(nodeGetter NodeGetter, nrtCli topologyclientset.Interface, args Args, tmconf TMConfig, options ...func(*NRTUpdater)) (*NRTUpdater, error) {
 	upd := NRTUpdater{
 		args:       args,
 		tmConfig:   tmconf,
 		stopChan:   make(chan struct{}),
 		nodeGetter: nodeGetter,
 		nrtCli:     nrtCli,
+		nodeIdent:  os.Getenv("NODE_NAME"),
+		events:     k8sevents.DisabledReporter{},
+		metrics:    metrics.Default(),
+	}
+	for _, opt := range options {
+		opt(&upd)
+	}
+	// the publishers don't use the NRT API
+	if nrtCli == nil && upd.publisher == nil {
+		return nil, fmt.Errorf("missing NRT client interface")
+	}
+	if upd.nodeIdent == "" {
+		upd.nodeIdent = args.Hostname
+	}
+	var err error
+	upd.labelPatterns, err = ParseMirrorPatterns(args.NodeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("node labels: %w", err)
+	}
+	upd.annotationPatterns, err = ParseMirrorPatterns(args.NodeAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("node annotations: %w", err)
 	}
+	if args.AuditLogFile != "" {
+		upd.auditLog = audit.New(args.AuditLogFile, int64(args.AuditLogMaxSizeMB)*1024*1024, args.AuditLogMaxBackups)
+	}
+	if notifier, ok := nodeGetter.(NodeChangeNotifier); ok {
+		upd.nodeChan = notifier.NodeChanges()
+	}
+	if upd.metaGetter == nil {
+		upd.metaGetter = nodeGetter
+	}
+	upd.watchNodeMetadata()
 	if args.PatchMode {
 		klog.Infof("operation mode: patch")
 		upd.sendObject = upd.sendObjectPatch
@@ -104,36 +285,197 @@ func NewNRTUpdater(nodeGetter NodeGetter, nrtCli topologyclientset.Interface, ar
 		klog.Infof("operation mode: get+update")
 		upd.sendObject = upd.sendObjectUpdate
 	}
+	upd.setupPublishers()
 	return &upd, nil
 }
 
 func (te *NRTUpdater) Update(ctx context.Context, info MonitorInfo) error {
-	return te.sendData(ctx, te.nrtCli, info)
+	ctx, span := tracing.Tracer().Start(tracing.ContextWithParent(ctx, info.SpanContext), "nrtupdater/update",
+		trace.WithAttributes(
+			attribute.Int64(tracing.AttrSequence, int64(info.Sequence)),
+			attribute.String(tracing.AttrUpdate, info.UpdateReason()),
+		),
+	)
+	defer span.End()
+
+	err := te.sendData(ctx, te.nrtCli, info)
+	if errors.Is(err, ErrPublishPending) {
+		// neither a success nor a failure yet: the outcome is reported once the data is delivered
+		te.publishPending = true
+		te.pendingSequence = info.Sequence
+		te.recordAudit(info, err)
+		return nil
+	}
+	tracing.SetError(span, err)
+	te.publishDone(ctx, info.Sequence, err)
+	te.recordAudit(info, err)
+	return err
+}
+
+// publishDone reports the outcome of the delivery of the update with the given sequence,
+// which includes the updates deferred before it, if any.
+func (te *NRTUpdater) publishDone(ctx context.Context, seq uint64, err error) {
+	te.publishPending = false
+	te.trackFailures(err)
+	if err != nil {
+		te.health.MarkFailure(health.StagePublish, err)
+		return
+	}
+	te.health.MarkSuccess(health.StagePublish)
+	if te.args.NoPublish {
+		return
+	}
+	if seq > 0 {
+		te.metrics.UpdatePublishedScanSequenceMetric(seq)
+	}
+	te.beat(ctx)
+}
+
+// deferredPublishDone reports the outcome of a delivery not triggered by Update, e.g. on timer.
+func (te *NRTUpdater) deferredPublishDone(ctx context.Context, err error) {
+	if err == nil && !te.publishPending {
+		// already accounted for, by an Update which delivered the data meanwhile
+		return
+	}
+	if err != nil {
+		klog.Warningf("failed to deliver the deferred updates: %v", err)
+	}
+	te.publishDone(ctx, te.pendingSequence, err)
+}
+
+// WithEventReporter makes the updater report the repeated publish failures.
+// A nil Reporter is ignored.
+func WithEventReporter(rep k8sevents.Reporter) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		if rep == nil {
+			return
+		}
+		te.events = rep
+	}
 }
 
+// WithMetrics makes the updater record its metrics in the given instance instead of the default one.
+// A nil instance is ignored.
+func WithMetrics(m *metrics.Metrics) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		if m == nil {
+			return
+		}
+		te.metrics = m
+	}
+}
+
+// WithHealth makes the updater report the outcome of the publishes to the given tracker.
+func WithHealth(tr *health.Tracker) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		te.health = tr
+	}
+}
+
+// WithDebugState makes the updater record the published objects in the given state.
+func WithDebugState(st *debugstate.State) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		te.debug = st
+	}
+}
+
+func (te *NRTUpdater) trackFailures(err error) {
+	if err == nil {
+		te.failureCount = 0
+		return
+	}
+	te.failureCount++
+	if te.failureCount == publishFailuresThreshold {
+		te.events.Warningf(k8sevents.ReasonPublishFailed, "failed to publish the topology %d times in a row: %v", te.failureCount, err)
+	}
+}
+
+// WithHeartbeat makes the updater notify the given Heartbeat after each successful publish.
+func WithHeartbeat(hb Heartbeat) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		te.heartbeat = hb
+	}
+}
+
+func (te *NRTUpdater) beat(ctx context.Context) {
+	if te.heartbeat == nil {
+		return
+	}
+	if err := te.heartbeat.Beat(ctx); err != nil {
+		klog.Warningf("failed to renew the heartbeat: %v", err)
+	}
+}
+
+func (te *NRTUpdater) recordAudit(info MonitorInfo, sendErr error) {
+	if te.auditLog == nil {
+		return
+	}
+	outcome := audit.OutcomeSuccess
+	if te.args.NoPublish {
+		outcome = audit.OutcomeDryRun
+	} else if errors.Is(sendErr, ErrPublishPending) {
+		outcome = audit.OutcomeQueued
+		sendErr = nil
+	} else if sendErr != nil {
+		outcome = audit.OutcomeFailure
+	}
+	err := te.auditLog.Record(time.Now(), te.args.Hostname, info.UpdateReason(), info.Annotations[podfingerprint.Annotation], info.Zones, outcome, sendErr)
+	if err != nil {
+		klog.Warningf("failed to record the audit log entry: %v", err)
+	}
+}
+
+// Stop requests Run to return. Safe to call multiple times.
 func (te *NRTUpdater) Stop() {
-	te.stopChan <- struct{}{}
+	te.stopOnce.Do(func() {
+		close(te.stopChan)
+	})
 }
 
-func (te *NRTUpdater) Run(infoChannel <-chan MonitorInfo, condChan chan v1.PodCondition) {
+// Run publishes the data received on infoChannel until the channel is closed or Stop is called.
+// Cancelling the context does not abort an in-flight write: the producer is expected to close
+// infoChannel on shutdown, so the data already sent is drained. It does abort the deliveries
+// deferred by the publishers, whose data is sent by the final flush instead.
+func (te *NRTUpdater) Run(ctx context.Context, infoChannel <-chan MonitorInfo, condChan chan v1.PodCondition) {
+	te.bindPublishers(ctx)
+	ctx = context.WithoutCancel(ctx)
 	for {
 		select {
-		case info := <-infoChannel:
+		case info, ok := <-infoChannel:
+			if !ok {
+				if err := te.Flush(ctx); err != nil {
+					klog.Warningf("%v", err)
+				}
+				klog.Infof("update drained at %v", time.Now())
+				return
+			}
 			tsBegin := time.Now()
 			condStatus := v1.ConditionTrue
-			if err := te.Update(context.Background(), info); err != nil {
+			if err := te.Update(ctx, info); err != nil {
 				klog.Warningf("failed to update: %v", err)
 				condStatus = v1.ConditionFalse
 			}
 			tsEnd := time.Now()
 
 			tsDiff := tsEnd.Sub(tsBegin)
-			metrics.UpdateOperationDelayMetric("node_resource_object_update", RTEUpdateReactive, float64(tsDiff.Milliseconds()))
-			if te.args.Oneshot {
-				break
-			}
+			te.metrics.UpdateOperationDelayMetric("node_resource_object_update", RTEUpdateReactive, float64(tsDiff.Milliseconds()))
 			podreadiness.SetCondition(condChan, podreadiness.NodeTopologyUpdated, condStatus)
+		case <-te.nodeChan:
+			if err := te.RefreshOwnerReferences(ctx); err != nil {
+				klog.Warningf("failed to refresh owner references: %v", err)
+			}
+		case <-te.metaChan:
+			if err := te.RefreshNodeMetadata(ctx); err != nil {
+				klog.Warningf("failed to refresh node metadata: %v", err)
+			}
+		case err := <-te.publishOutcomes:
+			te.deferredPublishDone(ctx, err)
+		case <-te.mirrorOutcomes:
+			// best effort, like the mirror publish: the publisher already logged the failures
 		case <-te.stopChan:
+			if err := te.Flush(ctx); err != nil {
+				klog.Warningf("%v", err)
+			}
 			klog.Infof("update stop at %v", time.Now())
 			return
 		}
@@ -143,10 +485,29 @@ func (te *NRTUpdater) Run(infoChannel <-chan MonitorInfo, condChan chan v1.PodCo
 func (te *NRTUpdater) sendData(ctx context.Context, cli topologyclientset.Interface, info MonitorInfo) error {
 	klog.V(7).Infof("update: sending zone: %v", dump.Object(info.Zones))
 	if te.args.NoPublish {
+		te.dryRun(ctx, info)
 		return nil
 	}
-	_, err := te.sendObject(ctx, cli, info)
-	return err
+	nrtObj, err := te.sendObject(ctx, cli, info)
+	if err != nil {
+		return err
+	}
+	te.debug.Record(debugstate.KeyNRT, nrtObj)
+	if te.mirror != nil {
+		// best effort: the main destination is the source of truth
+		spanCtx, span := startWriteSpan(ctx, "mirror", info)
+		span.SetAttributes(attribute.String(tracing.AttrPublisher, te.mirror.Name()))
+		err := te.mirror.Publish(spanCtx, nrtObj)
+		if errors.Is(err, ErrPublishPending) {
+			err = nil
+		}
+		tracing.SetError(span, err)
+		span.End()
+		if err != nil {
+			klog.Warningf("failed to publish to %s: %v", te.mirror.Name(), err)
+		}
+	}
+	return nil
 }
 
 type NRTPatchInfo struct {
@@ -193,25 +554,31 @@ func (te *NRTUpdater) patchNRT(ctx context.Context, cli topologyclientset.Interf
 	// falling back to the update path
 	nrtNew := te.prevNRT.DeepCopy()
 	te.updateNRTInfo(nrtNew, info)
+	te.updateNodeMetadata(ctx, nrtNew)
 	te.updateOwnerReferences(ctx, nrtNew)
 
 	patchInfo, reason, err := MakeNRTPatch(te.prevNRT, nrtNew)
 	if err != nil {
-		metrics.UpdateNodeResourceTopologyPatchFailuresMetric(reason)
+		te.metrics.UpdateNodeResourceTopologyPatchFailuresMetric(reason)
 		klog.Infof("failed to create a patch for the APIServer: %v", err)
 		return nil, err
 	}
 
 	ratio := patchInfo.SizeRatio()
 	klog.V(7).Infof("nrtupdater patch size %d bytes, full object %d bytes, ratio %.2f", len(patchInfo.Patch), patchInfo.FullObjBytes, ratio)
-	metrics.UpdateNodeResourceTopologyPatchSizeRatioMetric(ratio)
+	te.metrics.UpdateNodeResourceTopologyPatchSizeRatioMetric(ratio)
 
 	// The NodeResourceTopology API types lack patchStrategy/patchMergeKey struct tags,
 	// so strategic merge patch would fall back to JSON merge patch behavior anyway.
 	// We use MergePatchType to match the actual semantics.
-	nrtUpdated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Patch(ctx, te.prevNRT.Name, types.MergePatchType, patchInfo.Patch, metav1.PatchOptions{})
+	spanCtx, span := startWriteSpan(ctx, "patch", info)
+	tsBegin := time.Now()
+	nrtUpdated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Patch(spanCtx, te.prevNRT.Name, types.MergePatchType, patchInfo.Patch, metav1.PatchOptions{})
+	te.metrics.ObservePublishDuration("patch", info.UpdateReason(), time.Since(tsBegin))
+	tracing.SetError(span, err)
+	span.End()
 	if err != nil {
-		metrics.UpdateNodeResourceTopologyPatchFailuresMetric("send_patch")
+		te.metrics.UpdateNodeResourceTopologyPatchFailuresMetric("send_patch")
 		klog.Infof("failed to send a patch to the APIServer: %v", err)
 		return nil, err
 	}
@@ -257,13 +624,19 @@ func (te *NRTUpdater) sendObjectUpdate(ctx context.Context, cli topologyclientse
 			},
 		}
 		te.updateNRTInfo(&nrtNew, info)
+		te.updateNodeMetadata(ctx, &nrtNew)
 		te.updateOwnerReferences(ctx, &nrtNew)
 
-		nrtCreated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Create(ctx, &nrtNew, metav1.CreateOptions{})
+		spanCtx, span := startWriteSpan(ctx, "create", info)
+		tsBegin := time.Now()
+		nrtCreated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Create(spanCtx, &nrtNew, metav1.CreateOptions{})
+		te.metrics.ObservePublishDuration("create", info.UpdateReason(), time.Since(tsBegin))
+		tracing.SetError(span, err)
+		span.End()
 		if err != nil {
 			return nil, fmt.Errorf("update failed for NRT instance: %w", err)
 		}
-		metrics.UpdateNodeResourceTopologyWritesMetric("create", info.UpdateReason())
+		te.metrics.UpdateNodeResourceTopologyWritesMetric("create", info.UpdateReason())
 		klog.V(2).Infof("nrtupdater created NRT instance: %v", dump.Object(nrtCreated))
 		return nrtCreated, nil
 	}
@@ -274,20 +647,45 @@ func (te *NRTUpdater) sendObjectUpdate(ctx context.Context, cli topologyclientse
 
 	nrtMutated := nrt.DeepCopy()
 	te.updateNRTInfo(nrtMutated, info)
+	te.updateNodeMetadata(ctx, nrtMutated)
 	te.updateOwnerReferences(ctx, nrtMutated)
 
-	nrtUpdated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Update(ctx, nrtMutated, metav1.UpdateOptions{})
+	spanCtx, span := startWriteSpan(ctx, "update", info)
+	tsBegin := time.Now()
+	nrtUpdated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Update(spanCtx, nrtMutated, metav1.UpdateOptions{})
+	te.metrics.ObservePublishDuration("update", info.UpdateReason(), time.Since(tsBegin))
+	tracing.SetError(span, err)
+	span.End()
 	if err != nil {
 		return nil, fmt.Errorf("update failed for NRT instance: %w", err)
 	}
-	metrics.UpdateNodeResourceTopologyWritesMetric("update", info.UpdateReason())
+	te.metrics.UpdateNodeResourceTopologyWritesMetric("update", info.UpdateReason())
 	klog.V(7).Infof("nrtupdater changed CRD instance: %v", dump.Object(nrtUpdated))
 	return nrtUpdated, nil
 }
 
+// startWriteSpan traces a single write of the NRT data.
+func startWriteSpan(ctx context.Context, operation string, info MonitorInfo) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "nrtupdater/"+operation, trace.WithAttributes(
+		attribute.String(tracing.AttrOperation, operation),
+		attribute.String(tracing.AttrUpdate, info.UpdateReason()),
+	))
+}
+
 func (te *NRTUpdater) updateNRTInfo(nrt *v1alpha2.NodeResourceTopology, info MonitorInfo) {
 	nrt.Annotations = k8sannotations.Merge(nrt.Annotations, info.Annotations)
 	nrt.Annotations[k8sannotations.RTEUpdate] = info.UpdateReason()
+	if info.Sequence > 0 {
+		nrt.Annotations[k8sannotations.ScanSequence] = strconv.FormatUint(info.Sequence, 10)
+	}
+	if !info.ScanTime.IsZero() {
+		nrt.Annotations[k8sannotations.ScanTimestamp] = info.ScanTime.UTC().Format(time.RFC3339Nano)
+	}
+	if nrt.Labels == nil {
+		nrt.Labels = make(map[string]string)
+	}
+	nrt.Labels[LabelNodeIdentity] = NodeIdentity(te.nodeIdent)
+	nrt.Annotations[AnnotationNodeName] = te.nodeIdent
 	nrt.Zones = info.Zones.DeepCopy()
 	nrt.Attributes = info.Attributes.DeepCopy()
 	nrt.Attributes = append(nrt.Attributes, te.makeAttributes()...)
@@ -299,13 +697,21 @@ func (te *NRTUpdater) updateNRTInfo(nrt *v1alpha2.NodeResourceTopology, info Mon
 // Check nrt.OwnerReferences for Node references and update it so it has only one Node reference,
 // the one to the Node with the same name as the NRT.
 func (te *NRTUpdater) updateOwnerReferences(ctx context.Context, nrt *v1alpha2.NodeResourceTopology) {
-	node, err := te.nodeGetter.Get(ctx, nrt.Name, metav1.GetOptions{})
+	ownerRefs, ok := te.makeOwnerReferences(ctx, nrt.Name)
+	if !ok {
+		return
+	}
+	nrt.OwnerReferences = ownerRefs
+}
+
+func (te *NRTUpdater) makeOwnerReferences(ctx context.Context, nodeName string) ([]metav1.OwnerReference, bool) {
+	node, err := te.nodeGetter.Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		if errors.Is(err, NotConfigured) {
-			return
+			return nil, false
 		}
-		klog.V(7).Infof("nrtupdater unable to get Node %s. Can't add Owner reference. error: %v", nrt.Name, err)
-		return
+		klog.V(7).Infof("nrtupdater unable to get Node %s. Can't add Owner reference. error: %v", nodeName, err)
+		return nil, false
 	}
 	nodeReference := metav1.OwnerReference{
 		APIVersion: "v1",
@@ -313,8 +719,91 @@ func (te *NRTUpdater) updateOwnerReferences(ctx context.Context, nrt *v1alpha2.N
 		Name:       node.Name,
 		UID:        node.UID,
 	}
+	return []metav1.OwnerReference{nodeReference}, true
+}
+
+// RefreshOwnerReferences patches the NRT object owner references to point to the current Node object.
+// This is needed when the Node is re-created, because the owner references would refer to a stale UID.
+func (te *NRTUpdater) RefreshOwnerReferences(ctx context.Context) error {
+	if te.args.NoPublish || te.publisher != nil {
+		return nil
+	}
+	ownerRefs, ok := te.makeOwnerReferences(ctx, te.args.Hostname)
+	if !ok {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"ownerReferences": ownerRefs,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	nrtUpdated, err := te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Patch(ctx, te.args.Hostname, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		// nothing to do, the next update will create the object with the correct references
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if te.prevNRT != nil {
+		te.prevNRT = nrtUpdated
+	}
+	klog.V(2).Infof("nrtupdater refreshed owner references: %v", ownerRefs)
+	return nil
+}
 
-	nrt.OwnerReferences = []metav1.OwnerReference{nodeReference}
+// CleanupStale handles the NRT objects previously written by the exporter running on this node,
+// but with a name different from the current one, e.g. because the hostname changed.
+// Depending on the configuration, the stale objects are deleted or marked with AnnotationStale.
+// The objects are found by LabelNodeIdentity. The objects written by older versions have no such label:
+// among them, only the one named after the node is recognized, because that was the default name.
+// Unlabelled objects with any other name can't be attributed to this node, so they are left alone.
+func (te *NRTUpdater) CleanupStale(ctx context.Context) error {
+	if te.args.StaleCleanup == "" || te.args.StaleCleanup == StaleCleanupDisabled || te.args.NoPublish || te.publisher != nil {
+		return nil
+	}
+	sel := labels.SelectorFromSet(labels.Set{LabelNodeIdentity: NodeIdentity(te.nodeIdent)})
+	nrtList, err := te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().List(ctx, metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return fmt.Errorf("cannot list NRT objects for node %q: %w", te.nodeIdent, err)
+	}
+	if te.nodeIdent != te.args.Hostname {
+		nrt, err := te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Get(ctx, te.nodeIdent, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("cannot get NRT object %q: %w", te.nodeIdent, err)
+		}
+		if err == nil {
+			if _, ok := nrt.Labels[LabelNodeIdentity]; !ok {
+				nrtList.Items = append(nrtList.Items, *nrt)
+			}
+		}
+	}
+	var errs []error
+	for idx := range nrtList.Items {
+		nrt := &nrtList.Items[idx]
+		if nrt.Name == te.args.Hostname {
+			continue
+		}
+		switch te.args.StaleCleanup {
+		case StaleCleanupDelete:
+			err = te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Delete(ctx, nrt.Name, metav1.DeleteOptions{})
+			if apierrors.IsNotFound(err) {
+				err = nil
+			}
+		case StaleCleanupMark:
+			patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, AnnotationStale))
+			_, err = te.nrtCli.TopologyV1alpha2().NodeResourceTopologies().Patch(ctx, nrt.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot %s stale NRT %q: %w", te.args.StaleCleanup, nrt.Name, err))
+			continue
+		}
+		klog.Infof("nrtupdater stale NRT %q for node %q: %s", nrt.Name, te.nodeIdent, te.args.StaleCleanup)
+	}
+	return errors.Join(errs...)
 }
 
 func (te *NRTUpdater) makeAttributes() v1alpha2.AttributeList {
@@ -20,14 +20,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientk8sfake "k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 
@@ -35,6 +41,15 @@ import (
 
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+	"github.com/k8stopologyawareschedwg/podfingerprint"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/audit"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/debugstate"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/health"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sannotations"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sevents"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 )
 
 var nrtResource = schema.GroupVersionResource{Group: "topology.node.k8s.io", Version: "v1alpha2", Resource: "noderesourcetopologies"}
@@ -80,7 +95,7 @@ func testUpdateTMPolicy(t *testing.T, patchMode bool) {
 
 	var err error
 	k8sClient := clientk8sfake.NewSimpleClientset()
-	nodeGetter, err := NewCachedNodeGetter(k8sClient, context.Background())
+	nodeGetter, err := NewInformerNodeGetter(context.Background(), k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
 	if err != nil {
 		t.Fatalf("failed to create node getter: %v", err)
 	}
@@ -226,7 +241,7 @@ func testUpdateOwnerReferences(t *testing.T, patchMode bool) {
 	cli := fake.NewSimpleClientset()
 	var err error
 	k8sClient := clientk8sfake.NewSimpleClientset(&node)
-	nodeGetter, err := NewCachedNodeGetter(k8sClient, context.Background())
+	nodeGetter, err := NewInformerNodeGetter(context.Background(), k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
 	if err != nil {
 		t.Fatalf("failed to create node getter: %v", err)
 	}
@@ -497,7 +512,7 @@ func TestPatchMode(t *testing.T) {
 
 	cli := fake.NewSimpleClientset()
 	k8sClient := clientk8sfake.NewSimpleClientset()
-	nodeGetter, err := NewCachedNodeGetter(k8sClient, context.Background())
+	nodeGetter, err := NewInformerNodeGetter(context.Background(), k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
 	if err != nil {
 		t.Fatalf("failed to create node getter: %v", err)
 	}
@@ -583,7 +598,7 @@ func TestPatchModeFallbackOnError(t *testing.T) {
 
 	cli := fake.NewSimpleClientset()
 	k8sClient := clientk8sfake.NewSimpleClientset()
-	nodeGetter, err := NewCachedNodeGetter(k8sClient, context.Background())
+	nodeGetter, err := NewInformerNodeGetter(context.Background(), k8shelpers.NewNodeScopedInformerFactory(k8sClient, nodeName, 0), nodeName)
 	if err != nil {
 		t.Fatalf("failed to create node getter: %v", err)
 	}
@@ -706,3 +721,421 @@ func checkOwnerReferences(t *testing.T, obj runtime.Object, expected metav1.Owne
 		t.Fatalf("unexpected node OwnerReference. got=%+#v expected=%+#v", nodeReferences[0], expected)
 	}
 }
+
+func TestCleanupStale(t *testing.T) {
+	nodeName := "test-node"
+	t.Setenv("NODE_NAME", nodeName)
+
+	makeNRT := func(name, nodeIdent string) *v1alpha2.NodeResourceTopology {
+		nrt := &v1alpha2.NodeResourceTopology{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		}
+		if nodeIdent != "" {
+			nrt.Labels = map[string]string{
+				LabelNodeIdentity: NodeIdentity(nodeIdent),
+			}
+		}
+		return nrt
+	}
+
+	type testCase struct {
+		name         string
+		mode         string
+		expectedGone []string
+		expectedMark []string
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name: "disabled",
+			mode: StaleCleanupDisabled,
+		},
+		{
+			name:         "delete",
+			mode:         StaleCleanupDelete,
+			expectedGone: []string{"old-name", nodeName},
+		},
+		{
+			name:         "mark",
+			mode:         StaleCleanupMark,
+			expectedMark: []string{"old-name", nodeName},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			cli := fake.NewSimpleClientset(
+				makeNRT("new-name", nodeName),
+				makeNRT("old-name", nodeName),
+				makeNRT("other-node", "other-node"),
+				// written by older versions, before the node identity label was introduced
+				makeNRT(nodeName, ""),
+				makeNRT("unlabelled-name", ""),
+			)
+			nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: "new-name", StaleCleanup: tcase.mode}, TMConfig{})
+			if err != nil {
+				t.Fatalf("failed to create NRT updater: %v", err)
+			}
+
+			err = nrtUpd.CleanupStale(context.TODO())
+			if err != nil {
+				t.Fatalf("cleanup failed: %v", err)
+			}
+
+			gone := make(map[string]bool)
+			for _, name := range tcase.expectedGone {
+				gone[name] = true
+			}
+			marked := make(map[string]bool)
+			for _, name := range tcase.expectedMark {
+				marked[name] = true
+			}
+			for _, name := range []string{"new-name", "old-name", "other-node", nodeName, "unlabelled-name"} {
+				obj, err := cli.Tracker().Get(nrtResource, "", name)
+				if gone[name] {
+					if err == nil {
+						t.Errorf("expected %q to be deleted", name)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("failed to get NRT %q: %v", name, err)
+				}
+				nrtObj := obj.(*v1alpha2.NodeResourceTopology)
+				_, isMarked := nrtObj.Annotations[AnnotationStale]
+				if isMarked != marked[name] {
+					t.Errorf("unexpected stale mark for %q: got %v expected %v", name, isMarked, marked[name])
+				}
+			}
+		})
+	}
+}
+
+func TestNodeIdentityLabel(t *testing.T) {
+	for _, mode := range updateModes {
+		t.Run(mode.name, func(t *testing.T) {
+			nodeName := "test-node"
+			t.Setenv("NODE_NAME", nodeName)
+
+			cli := fake.NewSimpleClientset()
+			nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: "overridden-name", PatchMode: mode.patchMode}, TMConfig{})
+			if err != nil {
+				t.Fatalf("failed to create NRT updater: %v", err)
+			}
+			for range 2 { // make sure we exercise the patch path, if enabled
+				err = nrtUpd.Update(context.TODO(), MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}})
+				if err != nil {
+					t.Fatalf("update failed: %v", err)
+				}
+			}
+
+			obj, err := cli.Tracker().Get(nrtResource, "", "overridden-name")
+			if err != nil {
+				t.Fatalf("failed to get the NRT object from tracker: %v", err)
+			}
+			nrtObj := obj.(*v1alpha2.NodeResourceTopology)
+			if got := nrtObj.Labels[LabelNodeIdentity]; got != NodeIdentity(nodeName) {
+				t.Errorf("unexpected node identity label: got %q expected %q", got, NodeIdentity(nodeName))
+			}
+			if got := nrtObj.Annotations[AnnotationNodeName]; got != nodeName {
+				t.Errorf("unexpected node name annotation: got %q expected %q", got, nodeName)
+			}
+		})
+	}
+}
+
+func TestNodeIdentity(t *testing.T) {
+	longName := strings.Repeat("a", 253)
+	got := NodeIdentity(longName)
+	if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+		t.Errorf("invalid label value %q: %v", got, errs)
+	}
+	if NodeIdentity(longName) != got {
+		t.Errorf("unstable node identity")
+	}
+	if NodeIdentity(longName[1:]) == got {
+		t.Errorf("node identity collision")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	nodeName := "test-node"
+	existing := &v1alpha2.NodeResourceTopology{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            nodeName,
+			ResourceVersion: "42",
+		},
+		Zones: v1alpha2.ZoneList{{Name: "zone-old", Type: "node"}},
+	}
+	cli := fake.NewSimpleClientset(existing)
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: nodeName, NoPublish: true}, TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	for _, zoneName := range []string{"zone-0", "zone-1"} {
+		err = nrtUpd.Update(context.TODO(), MonitorInfo{Zones: v1alpha2.ZoneList{{Name: zoneName, Type: "node"}}})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if nrtUpd.dryRunPrev == nil || len(nrtUpd.dryRunPrev.Zones) != 1 || nrtUpd.dryRunPrev.Zones[0].Name != zoneName {
+			t.Errorf("unexpected tracked object: %#v", nrtUpd.dryRunPrev)
+		}
+		// starting from the fetched object keeps the server-side metadata out of the diff
+		if nrtUpd.dryRunPrev.ResourceVersion != "42" {
+			t.Errorf("tracked object lost the baseline metadata: %#v", nrtUpd.dryRunPrev.ObjectMeta)
+		}
+	}
+
+	actions := cli.Actions()
+	if len(actions) != 1 || actions[0].GetVerb() != "get" {
+		t.Errorf("expected a single get, got %v", actions)
+	}
+	obj, err := cli.Tracker().Get(nrtResource, "", nodeName)
+	if err != nil {
+		t.Fatalf("failed to get the NRT object from tracker: %v", err)
+	}
+	if nrtObj := obj.(*v1alpha2.NodeResourceTopology); nrtObj.Zones[0].Name != "zone-old" {
+		t.Errorf("NRT object modified in dry-run mode: %v", nrtObj.Zones)
+	}
+}
+
+func TestDryRunIdenticalScans(t *testing.T) {
+	nodeName := "test-node"
+	existing := &v1alpha2.NodeResourceTopology{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodeName,
+			Annotations: map[string]string{AnnotationStale: "true"},
+		},
+		Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}},
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, fake.NewSimpleClientset(existing), Args{Hostname: nodeName, NoPublish: true}, TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	scanTime := time.Now()
+	var prev *v1alpha2.NodeResourceTopology
+	for seq := uint64(1); seq <= 2; seq++ {
+		info := MonitorInfo{
+			Zones:    v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}},
+			Sequence: seq,
+			ScanTime: scanTime.Add(time.Duration(seq) * time.Second),
+		}
+		prev = nrtUpd.dryRunPrev
+		err = nrtUpd.Update(context.TODO(), info)
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+	}
+
+	patchInfo, _, err := makeDryRunPatch(prev, nrtUpd.dryRunPrev)
+	if err != nil {
+		t.Fatalf("failed to compute the diff: %v", err)
+	}
+	if string(patchInfo.Patch) != "{}" {
+		t.Errorf("identical scans produced a diff: %s", string(patchInfo.Patch))
+	}
+	if nrtUpd.dryRunPrev.Annotations[k8sannotations.ScanSequence] != "2" {
+		t.Errorf("volatile annotations dropped from the would-be object: %v", nrtUpd.dryRunPrev.Annotations)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	cli := fake.NewSimpleClientset()
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: "test-node", AuditLogFile: auditPath}, TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	info := MonitorInfo{
+		Zones:       v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}},
+		Annotations: map[string]string{podfingerprint.Annotation: "pfp0v0011223344"},
+	}
+	err = nrtUpd.Update(context.TODO(), info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	cli.PrependReactor("update", "noderesourcetopologies", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("simulated update failure")
+	})
+	info.Timer = true
+	err = nrtUpd.Update(context.TODO(), info)
+	if err == nil {
+		t.Fatalf("update unexpectedly succeeded")
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("failed to read the audit log: %v", err)
+	}
+	var entries []audit.Entry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry audit.Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to decode the audit entry %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("unexpected audit entries: %+v", entries)
+	}
+	if entries[0].Outcome != audit.OutcomeSuccess || entries[0].Trigger != RTEUpdateReactive || entries[0].PodFingerprint != "pfp0v0011223344" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Outcome != audit.OutcomeFailure || entries[1].Trigger != RTEUpdatePeriodic || entries[1].Error == "" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+}
+
+type fakeHeartbeat struct {
+	beats int
+}
+
+func (fh *fakeHeartbeat) Beat(_ context.Context) error {
+	fh.beats++
+	return nil
+}
+
+func TestHeartbeatOnSuccessfulPublish(t *testing.T) {
+	cli := fake.NewSimpleClientset()
+	hb := &fakeHeartbeat{}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: "test-node"}, TMConfig{}, WithHeartbeat(hb))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	info := MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}}
+	err = nrtUpd.Update(context.TODO(), info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if hb.beats != 1 {
+		t.Errorf("expected a beat after a successful publish, got %d", hb.beats)
+	}
+
+	cli.PrependReactor("update", "noderesourcetopologies", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("simulated update failure")
+	})
+	err = nrtUpd.Update(context.TODO(), info)
+	if err == nil {
+		t.Fatalf("update unexpectedly succeeded")
+	}
+	if hb.beats != 1 {
+		t.Errorf("unexpected beat after a failed publish, got %d", hb.beats)
+	}
+}
+
+type fakeReporter struct {
+	reasons []string
+}
+
+func (fr *fakeReporter) Warningf(reason, messageFmt string, args ...interface{}) {
+	fr.reasons = append(fr.reasons, reason)
+}
+
+func TestEventOnRepeatedPublishFailures(t *testing.T) {
+	cli := fake.NewSimpleClientset()
+	rep := &fakeReporter{}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: "test-node"}, TMConfig{}, WithEventReporter(rep))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	failing := true
+	for _, verb := range []string{"create", "update"} {
+		cli.PrependReactor(verb, "noderesourcetopologies", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if failing {
+				return true, nil, fmt.Errorf("simulated %s failure", action.GetVerb())
+			}
+			return false, nil, nil
+		})
+	}
+
+	info := MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}}
+	for idx := 0; idx < publishFailuresThreshold-1; idx++ {
+		_ = nrtUpd.Update(context.TODO(), info)
+	}
+	if len(rep.reasons) != 0 {
+		t.Fatalf("unexpected events before the threshold: %v", rep.reasons)
+	}
+	_ = nrtUpd.Update(context.TODO(), info)
+	_ = nrtUpd.Update(context.TODO(), info)
+	if len(rep.reasons) != 1 || rep.reasons[0] != k8sevents.ReasonPublishFailed {
+		t.Fatalf("expected exactly one %q event, got %v", k8sevents.ReasonPublishFailed, rep.reasons)
+	}
+
+	failing = false
+	err = nrtUpd.Update(context.TODO(), info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	failing = true
+	for idx := 0; idx < publishFailuresThreshold-1; idx++ {
+		_ = nrtUpd.Update(context.TODO(), info)
+	}
+	if len(rep.reasons) != 1 {
+		t.Fatalf("expected the failure count to restart after a success, got %v", rep.reasons)
+	}
+	_ = nrtUpd.Update(context.TODO(), info)
+	if len(rep.reasons) != 2 {
+		t.Errorf("expected a new event once the threshold is reached again, got %v", rep.reasons)
+	}
+}
+
+func TestMetricsInstance(t *testing.T) {
+	m, err := metrics.New("test-node", nil)
+	if err != nil {
+		t.Fatalf("failed to create the metrics: %v", err)
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, fake.NewSimpleClientset(), Args{Hostname: "test-node"}, TMConfig{}, WithMetrics(m))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	info := MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}, Sequence: 5}
+	err = nrtUpd.Update(context.TODO(), info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if got := testutil.ToFloat64(m.NodeResourceTopologyWrites.WithLabelValues("test-node", "create", RTEUpdateReactive)); got != 1 {
+		t.Errorf("unexpected writes: got %v expected 1", got)
+	}
+	if got := testutil.ToFloat64(m.PublishedScanSequence.WithLabelValues("test-node")); got != 5 {
+		t.Errorf("unexpected published sequence: got %v expected 5", got)
+	}
+}
+
+func TestHealthAndDebugStateInstances(t *testing.T) {
+	tracker := health.NewTracker(health.StagePublish)
+	st := debugstate.New()
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, fake.NewSimpleClientset(), Args{Hostname: "test-node"}, TMConfig{},
+		WithHealth(tracker),
+		WithDebugState(st),
+	)
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	info := MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}}
+	err = nrtUpd.Update(context.TODO(), info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if rep := tracker.Report(); !rep.Ready {
+		t.Errorf("publish not reported healthy: %+v", rep)
+	}
+	data, ok := st.Get(debugstate.KeyNRT)
+	if !ok {
+		t.Fatalf("published object not recorded")
+	}
+	var nrt v1alpha2.NodeResourceTopology
+	if err := json.Unmarshal(data, &nrt); err != nil {
+		t.Fatalf("cannot decode the recorded object: %v", err)
+	}
+	if nrt.Name != "test-node" {
+		t.Errorf("unexpected recorded object %q", nrt.Name)
+	}
+}
@@ -0,0 +1,384 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/dump"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/tracing"
+)
+
+const (
+	PublisherNRT            = "nrt"
+	PublisherConfigMap      = "configmap"
+	PublisherNodeAnnotation = "nodeannotation"
+	PublisherFile           = "file"
+	PublisherWebhook        = "webhook"
+)
+
+const (
+	// ConfigMapPrefix is prepended to the node name to name the ConfigMap holding the topology data.
+	ConfigMapPrefix = "nrt-"
+	// ConfigMapDataKey is the ConfigMap data key holding the JSON-serialized NRT object.
+	ConfigMapDataKey = "nrt.json"
+	// AnnotationNRTData is the Node annotation holding the gzip-compressed, base64-encoded, JSON-serialized NRT object.
+	AnnotationNRTData = "k8stopoawareschedwg/nrt-data"
+)
+
+// the apiserver limits the total size of the annotations to 256 KiB. We can't use it all.
+const maxNodeAnnotationBytes = 128 * 1024
+
+// Publisher writes the NRT object built by the updater to a destination other than the NRT API.
+type Publisher interface {
+	Publish(ctx context.Context, nrt *v1alpha2.NodeResourceTopology) error
+	Name() string
+}
+
+func PublisherSupported() string {
+	pubs := []string{
+		PublisherNRT,
+		PublisherConfigMap,
+		PublisherNodeAnnotation,
+		PublisherFile,
+		PublisherWebhook,
+	}
+	return strings.Join(pubs, ",")
+}
+
+func PublisherIsSupported(value string) (string, error) {
+	val := strings.ToLower(value)
+	switch val {
+	case "", PublisherNRT, PublisherConfigMap, PublisherNodeAnnotation, PublisherFile, PublisherWebhook:
+		return val, nil
+	default:
+		return val, fmt.Errorf("unsupported publisher %q", value)
+	}
+}
+
+// PublisherNeedsAPI tells if the given publisher writes to the Kubernetes API server.
+func PublisherNeedsAPI(publisher string) bool {
+	switch publisher {
+	case PublisherFile, PublisherWebhook:
+		return false
+	default:
+		return true
+	}
+}
+
+// NewPublisher creates the Publisher selected by args. Returns nil if the NRT API
+// is selected, because the NRTUpdater handles it natively.
+func NewPublisher(args Args, cli kubernetes.Interface) (Publisher, error) {
+	switch args.Publisher {
+	case "", PublisherNRT:
+		return nil, nil
+	case PublisherConfigMap:
+		namespace := k8shelpers.NamespaceOrDefault(args.PublisherNamespace)
+		if namespace == "" {
+			return nil, fmt.Errorf("missing namespace for the %s publisher", PublisherConfigMap)
+		}
+		return &ConfigMapPublisher{cli: cli, namespace: namespace}, nil
+	case PublisherNodeAnnotation:
+		return &NodeAnnotationPublisher{cli: cli}, nil
+	case PublisherFile:
+		return NewFilePublisher(args)
+	case PublisherWebhook:
+		return NewWebhookPublisher(args)
+	default:
+		return nil, fmt.Errorf("unsupported publisher %q", args.Publisher)
+	}
+}
+
+// Flusher is implemented by the Publishers which buffer the data.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// ErrPublishPending is returned by the Publishers which accepted the data, but deferred its delivery.
+// It is not a failure, but the data is not published yet either.
+var ErrPublishPending = errors.New("publish pending")
+
+// AsyncPublisher is implemented by the Publishers which may defer the delivery, e.g. batching the data.
+// The outcome of the deliveries not triggered by Publish or Flush, e.g. on timer, is reported on the
+// channel returned by PublishOutcomes. These deliveries are aborted once the context given to
+// BindContext is cancelled.
+type AsyncPublisher interface {
+	PublishOutcomes() <-chan error
+	BindContext(ctx context.Context)
+}
+
+// NewMirrorPublisher creates the Publisher which receives the data alongside the main destination,
+// if configured. Returns nil otherwise.
+func NewMirrorPublisher(args Args) (Publisher, error) {
+	if args.WebhookURL == "" || args.Publisher == PublisherWebhook {
+		return nil, nil
+	}
+	return NewWebhookPublisher(args)
+}
+
+// WithMirrorPublisher makes the updater send the data also through the given Publisher, after
+// it has been successfully sent to the main destination. A nil Publisher is ignored.
+func WithMirrorPublisher(pub Publisher) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		if pub == nil {
+			return
+		}
+		te.mirror = pub
+	}
+}
+
+// Flush delivers the data deferred by the publishers, if any. Returns the outcome of the main publisher,
+// which is also reported like the outcome of Update.
+func (te *NRTUpdater) Flush(ctx context.Context) error {
+	var err error
+	if flusher, ok := te.publisher.(Flusher); ok {
+		err = flusher.Flush(ctx)
+		if err != nil || te.publishPending {
+			te.publishDone(ctx, te.pendingSequence, err)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to flush %s: %w", te.publisher.Name(), err)
+		}
+	}
+	if flusher, ok := te.mirror.(Flusher); ok {
+		// best effort, like the mirror publish
+		if err := flusher.Flush(ctx); err != nil {
+			klog.Warningf("failed to flush %s: %v", te.mirror.Name(), err)
+		}
+	}
+	return err
+}
+
+// WithPublisher makes the updater send the data through the given Publisher instead of the NRT API.
+// A nil Publisher is ignored.
+func WithPublisher(pub Publisher) func(*NRTUpdater) {
+	return func(te *NRTUpdater) {
+		if pub == nil {
+			return
+		}
+		te.publisher = pub
+	}
+}
+
+// setupPublishers switches to the configured publishers, if any.
+func (te *NRTUpdater) setupPublishers() {
+	if te.publisher != nil {
+		klog.Infof("operation mode: publish (%s)", te.publisher.Name())
+		te.sendObject = te.sendObjectPublish
+		if async, ok := te.publisher.(AsyncPublisher); ok {
+			te.publishOutcomes = async.PublishOutcomes()
+		}
+	}
+	if te.mirror != nil {
+		klog.Infof("mirroring updates to: %s", te.mirror.Name())
+		if async, ok := te.mirror.(AsyncPublisher); ok {
+			te.mirrorOutcomes = async.PublishOutcomes()
+		}
+	}
+}
+
+// bindPublishers makes the deferred deliveries of the publishers, if any, stop with the pipeline.
+func (te *NRTUpdater) bindPublishers(ctx context.Context) {
+	for _, pub := range []Publisher{te.publisher, te.mirror} {
+		if async, ok := pub.(AsyncPublisher); ok {
+			async.BindContext(ctx)
+		}
+	}
+}
+
+func (te *NRTUpdater) sendObjectPublish(ctx context.Context, _ topologyclientset.Interface, info MonitorInfo) (*v1alpha2.NodeResourceTopology, error) {
+	nrt := te.makeNRT(ctx, info)
+	spanCtx, span := startWriteSpan(ctx, "publish", info)
+	span.SetAttributes(attribute.String(tracing.AttrPublisher, te.publisher.Name()))
+	tsBegin := time.Now()
+	err := te.publisher.Publish(spanCtx, nrt)
+	te.metrics.ObservePublishDuration(te.publisher.Name(), info.UpdateReason(), time.Since(tsBegin))
+	if errors.Is(err, ErrPublishPending) {
+		span.SetAttributes(attribute.Bool(tracing.AttrPending, true))
+		span.End()
+		klog.V(7).Infof("nrtupdater queued NRT data (%s): %v", te.publisher.Name(), dump.Object(nrt))
+		return nrt, err
+	}
+	tracing.SetError(span, err)
+	span.End()
+	if err != nil {
+		return nil, fmt.Errorf("publish failed for NRT data (%s): %w", te.publisher.Name(), err)
+	}
+	te.metrics.UpdateNodeResourceTopologyWritesMetric(te.publisher.Name(), info.UpdateReason())
+	klog.V(7).Infof("nrtupdater published NRT data (%s): %v", te.publisher.Name(), dump.Object(nrt))
+	return nrt, nil
+}
+
+// makeNRT builds from scratch the NRT object to be sent.
+func (te *NRTUpdater) makeNRT(ctx context.Context, info MonitorInfo) *v1alpha2.NodeResourceTopology {
+	nrt := v1alpha2.NodeResourceTopology{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha2.SchemeGroupVersion.String(),
+			Kind:       "NodeResourceTopology",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        te.args.Hostname,
+			Annotations: make(map[string]string),
+		},
+	}
+	te.updateNRTInfo(&nrt, info)
+	te.updateNodeMetadata(ctx, &nrt)
+	te.updateOwnerReferences(ctx, &nrt)
+	return &nrt
+}
+
+// ConfigMapPublisher writes the JSON-serialized NRT object in a per-node ConfigMap.
+type ConfigMapPublisher struct {
+	cli       kubernetes.Interface
+	namespace string
+}
+
+func (pub *ConfigMapPublisher) Name() string {
+	return PublisherConfigMap
+}
+
+func (pub *ConfigMapPublisher) Publish(ctx context.Context, nrt *v1alpha2.NodeResourceTopology) error {
+	data, err := json.Marshal(nrt)
+	if err != nil {
+		return err
+	}
+	cms := pub.cli.CoreV1().ConfigMaps(pub.namespace)
+	cmName := ConfigMapPrefix + nrt.Name
+
+	cm, err := cms.Get(ctx, cmName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cmNew := corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cmName,
+				Namespace: pub.namespace,
+			},
+		}
+		updateConfigMap(&cmNew, nrt, data)
+		_, err = cms.Create(ctx, &cmNew, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	cmMutated := cm.DeepCopy()
+	updateConfigMap(cmMutated, nrt, data)
+	_, err = cms.Update(ctx, cmMutated, metav1.UpdateOptions{})
+	return err
+}
+
+// updateConfigMap makes cm reflect the given NRT object. The ConfigMap is owned by the exporter, so
+// its labels are replaced by the NRT ones: the labels of an earlier NRT object don't linger.
+func updateConfigMap(cm *corev1.ConfigMap, nrt *v1alpha2.NodeResourceTopology, data []byte) {
+	cm.Labels = maps.Clone(nrt.Labels)
+	if len(nrt.OwnerReferences) > 0 {
+		cm.OwnerReferences = nrt.OwnerReferences
+	}
+	cm.Data = map[string]string{
+		ConfigMapDataKey: string(data),
+	}
+}
+
+// NodeAnnotationPublisher writes the compressed NRT object in an annotation of the Node itself.
+type NodeAnnotationPublisher struct {
+	cli kubernetes.Interface
+}
+
+func (pub *NodeAnnotationPublisher) Name() string {
+	return PublisherNodeAnnotation
+}
+
+func (pub *NodeAnnotationPublisher) Publish(ctx context.Context, nrt *v1alpha2.NodeResourceTopology) error {
+	value, err := EncodeNRTAnnotation(nrt)
+	if err != nil {
+		return err
+	}
+	if len(value) > maxNodeAnnotationBytes {
+		return fmt.Errorf("encoded NRT data too large: %d bytes (max %d)", len(value), maxNodeAnnotationBytes)
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				AnnotationNRTData: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = pub.cli.CoreV1().Nodes().Patch(ctx, nrt.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// EncodeNRTAnnotation serializes the NRT object in the format used by the Node annotation publisher.
+func EncodeNRTAnnotation(nrt *v1alpha2.NodeResourceTopology) (string, error) {
+	data, err := json.Marshal(nrt)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write(data)
+	if err != nil {
+		return "", err
+	}
+	err = zw.Close()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeNRTAnnotation is the inverse of EncodeNRTAnnotation, meant for the consumers of the published data.
+func DecodeNRTAnnotation(value string) (*v1alpha2.NodeResourceTopology, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var nrt v1alpha2.NodeResourceTopology
+	err = json.NewDecoder(zr).Decode(&nrt)
+	if err != nil {
+		return nil, err
+	}
+	return &nrt, nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientk8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+)
+
+func TestNewPublisher(t *testing.T) {
+	type testCase struct {
+		name          string
+		args          Args
+		expectedName  string
+		expectedError bool
+	}
+
+	t.Setenv("REFERENCE_NAMESPACE", "")
+
+	for _, tcase := range []testCase{
+		{
+			name: "default",
+		},
+		{
+			name: "nrt",
+			args: Args{Publisher: PublisherNRT},
+		},
+		{
+			name:         "configmap",
+			args:         Args{Publisher: PublisherConfigMap, PublisherNamespace: "rte"},
+			expectedName: PublisherConfigMap,
+		},
+		{
+			name:          "configmap without namespace",
+			args:          Args{Publisher: PublisherConfigMap},
+			expectedError: true,
+		},
+		{
+			name:         "nodeannotation",
+			args:         Args{Publisher: PublisherNodeAnnotation},
+			expectedName: PublisherNodeAnnotation,
+		},
+		{
+			name:          "unsupported",
+			args:          Args{Publisher: "foobar"},
+			expectedError: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			pub, err := NewPublisher(tcase.args, clientk8sfake.NewSimpleClientset())
+			gotErr := (err != nil)
+			if gotErr != tcase.expectedError {
+				t.Fatalf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+			gotName := ""
+			if pub != nil {
+				gotName = pub.Name()
+			}
+			if gotName != tcase.expectedName {
+				t.Errorf("publisher mismatch: got %q expected %q", gotName, tcase.expectedName)
+			}
+		})
+	}
+}
+
+func TestConfigMapPublisher(t *testing.T) {
+	ctx := context.Background()
+	nodeName := "test-node"
+	namespace := "rte"
+
+	k8sClient := clientk8sfake.NewSimpleClientset()
+	cli := fake.NewSimpleClientset()
+	pub, err := NewPublisher(Args{Publisher: PublisherConfigMap, PublisherNamespace: namespace}, k8sClient)
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: nodeName}, TMConfig{}, WithPublisher(pub))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	for _, zoneName := range []string{"zone-0", "zone-1"} { // create, then update
+		err = nrtUpd.Update(ctx, MonitorInfo{Zones: v1alpha2.ZoneList{{Name: zoneName, Type: "node"}}})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+
+		cm, err := k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, ConfigMapPrefix+nodeName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get the ConfigMap: %v", err)
+		}
+		if cm.Labels[LabelNodeIdentity] != NodeIdentity(nodeName) {
+			t.Errorf("missing node identity label: %v", cm.Labels)
+		}
+		var nrt v1alpha2.NodeResourceTopology
+		err = json.Unmarshal([]byte(cm.Data[ConfigMapDataKey]), &nrt)
+		if err != nil {
+			t.Fatalf("failed to decode the ConfigMap data: %v", err)
+		}
+		if nrt.Name != nodeName || len(nrt.Zones) != 1 || nrt.Zones[0].Name != zoneName {
+			t.Errorf("unexpected NRT data: %#v", nrt)
+		}
+	}
+
+	if len(cli.Actions()) != 0 {
+		t.Errorf("unexpected NRT API actions: %v", cli.Actions())
+	}
+}
+
+func TestConfigMapPublisherReplacesLabels(t *testing.T) {
+	ctx := context.Background()
+	namespace := "rte"
+
+	k8sClient := clientk8sfake.NewSimpleClientset()
+	pub := &ConfigMapPublisher{cli: k8sClient, namespace: namespace}
+
+	nrt := makeTestNRT("zone-0")
+	nrt.Labels = map[string]string{"foo": "bar", "stale": "value"}
+	err := pub.Publish(ctx, nrt)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	nrt.Labels = map[string]string{"foo": "baz"}
+	err = pub.Publish(ctx, nrt)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	cm, err := k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, ConfigMapPrefix+nrt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the ConfigMap: %v", err)
+	}
+	if len(cm.Labels) != 1 || cm.Labels["foo"] != "baz" {
+		t.Errorf("unexpected labels: %v", cm.Labels)
+	}
+}
+
+func TestNodeAnnotationPublisher(t *testing.T) {
+	ctx := context.Background()
+	nodeName := "test-node"
+
+	k8sClient := clientk8sfake.NewSimpleClientset(makeNode(nodeName, "uid-1"))
+	cli := fake.NewSimpleClientset()
+	pub, err := NewPublisher(Args{Publisher: PublisherNodeAnnotation}, k8sClient)
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, Args{Hostname: nodeName, PatchMode: true}, TMConfig{}, WithPublisher(pub))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	err = nrtUpd.Update(ctx, MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	node, err := k8sClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the node: %v", err)
+	}
+	nrt, err := DecodeNRTAnnotation(node.Annotations[AnnotationNRTData])
+	if err != nil {
+		t.Fatalf("failed to decode the node annotation: %v", err)
+	}
+	if nrt.Name != nodeName || len(nrt.Zones) != 1 || nrt.Zones[0].Name != "zone-0" {
+		t.Errorf("unexpected NRT data: %#v", nrt)
+	}
+
+	if len(cli.Actions()) != 0 {
+		t.Errorf("unexpected NRT API actions: %v", cli.Actions())
+	}
+}
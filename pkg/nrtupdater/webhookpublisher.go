@@ -0,0 +1,385 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+const (
+	WebhookPayloadObject = "object"
+	WebhookPayloadPatch  = "patch"
+)
+
+const (
+	webhookRequestTimeout = 10 * time.Second
+	webhookRetryBase      = 500 * time.Millisecond
+	webhookRetryMax       = 10 * time.Second
+	// webhookOutcomesBuffer is the number of timer flush outcomes kept while the consumer is busy
+	webhookOutcomesBuffer = 16
+)
+
+var ErrWebhookRejected = errors.New("webhook rejected the request")
+
+// WebhookMessage is the body of the requests sent by the webhook publisher.
+type WebhookMessage struct {
+	Node  string        `json:"node"`
+	Items []WebhookItem `json:"items"`
+}
+
+// WebhookItem is a single update. The Data is the full NRT object if Type is "object",
+// or a JSON merge patch against the previous item if Type is "patch".
+type WebhookItem struct {
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func WebhookPayloadSupported() string {
+	payloads := []string{
+		WebhookPayloadObject,
+		WebhookPayloadPatch,
+	}
+	return strings.Join(payloads, ",")
+}
+
+func WebhookPayloadIsSupported(value string) (string, error) {
+	val := strings.ToLower(value)
+	switch val {
+	case "", WebhookPayloadObject, WebhookPayloadPatch:
+		return val, nil
+	default:
+		return val, fmt.Errorf("unsupported webhook payload %q", value)
+	}
+}
+
+// WebhookPublisher POSTs the NRT objects, or the patches between them, to a HTTP(S) endpoint.
+// Updates can be batched; a batch is sent when it is full or when the batch interval expires,
+// whatever comes first. Failed requests are retried with exponential backoff.
+// Publish returns ErrPublishPending for the updates only queued in a batch, and the outcome of the
+// batches sent on the expiration of the interval is reported through PublishOutcomes.
+// The latest data of a failed batch is queued again, so it is delivered with the next one.
+type WebhookPublisher struct {
+	url           string
+	client        *http.Client
+	payload       string
+	retries       int
+	retryBase     time.Duration
+	batchSize     int
+	batchInterval time.Duration
+	outcomes      chan error
+
+	// sendLock serializes the sends, so the batches are delivered in order. It is never held
+	// with lock, so Publish can queue updates while a batch is being sent.
+	sendLock sync.Mutex
+
+	lock sync.Mutex
+	// ctx is the parent of the sends on the expiration of the interval
+	ctx     context.Context
+	node    string
+	pending []WebhookItem
+	timer   *time.Timer
+	prevNRT *v1alpha2.NodeResourceTopology
+}
+
+func NewWebhookPublisher(args Args) (*WebhookPublisher, error) {
+	if args.WebhookURL == "" {
+		return nil, fmt.Errorf("missing webhook URL")
+	}
+	endpoint, err := url.Parse(args.WebhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported webhook URL scheme %q", endpoint.Scheme)
+	}
+	payload, err := WebhookPayloadIsSupported(args.WebhookPayload)
+	if err != nil {
+		return nil, err
+	}
+	if payload == "" {
+		payload = WebhookPayloadObject
+	}
+	tlsConfig, err := makeWebhookTLSConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookPublisher{
+		url: args.WebhookURL,
+		client: &http.Client{
+			Timeout: webhookRequestTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		payload:       payload,
+		retries:       args.WebhookRetries,
+		retryBase:     webhookRetryBase,
+		batchSize:     args.WebhookBatchSize,
+		batchInterval: args.WebhookBatchInterval,
+		outcomes:      make(chan error, webhookOutcomesBuffer),
+		ctx:           context.Background(),
+	}, nil
+}
+
+func makeWebhookTLSConfig(args Args) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if args.WebhookCAFile != "" {
+		caData, err := os.ReadFile(args.WebhookCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read webhook CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no valid certificates in webhook CA file %q", args.WebhookCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (args.WebhookCertFile == "") != (args.WebhookKeyFile == "") {
+		return nil, fmt.Errorf("webhook client certificate and key must be given together")
+	}
+	if args.WebhookCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(args.WebhookCertFile, args.WebhookKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load webhook client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (pub *WebhookPublisher) Name() string {
+	return PublisherWebhook
+}
+
+func (pub *WebhookPublisher) Publish(ctx context.Context, nrt *v1alpha2.NodeResourceTopology) error {
+	pub.lock.Lock()
+	item, err := pub.makeItem(nrt)
+	if err != nil {
+		pub.lock.Unlock()
+		return err
+	}
+	pub.node = nrt.Name
+	pub.pending = append(pub.pending, item)
+	if len(pub.pending) < pub.batchSize {
+		if pub.batchInterval > 0 && pub.timer == nil {
+			pub.timer = time.AfterFunc(pub.batchInterval, pub.flushOnTimer)
+		}
+		pub.lock.Unlock()
+		return ErrPublishPending
+	}
+	pub.lock.Unlock()
+	return pub.Flush(ctx)
+}
+
+// PublishOutcomes returns the channel receiving the outcome of each batch sent on the expiration
+// of the batch interval. If the consumer falls behind, the oldest outcomes are dropped.
+func (pub *WebhookPublisher) PublishOutcomes() <-chan error {
+	return pub.outcomes
+}
+
+// BindContext sets the context of the batches sent on the expiration of the interval,
+// which are aborted once it is cancelled.
+func (pub *WebhookPublisher) BindContext(ctx context.Context) {
+	pub.lock.Lock()
+	defer pub.lock.Unlock()
+	pub.ctx = ctx
+}
+
+// Flush sends the pending updates, if any.
+func (pub *WebhookPublisher) Flush(ctx context.Context) error {
+	pub.sendLock.Lock()
+	defer pub.sendLock.Unlock()
+
+	pub.lock.Lock()
+	if pub.timer != nil {
+		pub.timer.Stop()
+		pub.timer = nil
+	}
+	if len(pub.pending) == 0 {
+		pub.lock.Unlock()
+		return nil
+	}
+	msg := WebhookMessage{
+		Node:  pub.node,
+		Items: pub.pending,
+	}
+	pub.pending = nil
+	pub.lock.Unlock()
+
+	err := pub.send(ctx, msg)
+	if err != nil {
+		pub.lock.Lock()
+		pub.resyncLocked(msg.Items)
+		pub.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// resyncLocked handles the failed send of the given items, queueing again the latest data.
+// Must be called with the lock held.
+func (pub *WebhookPublisher) resyncLocked(failed []WebhookItem) {
+	if pub.payload != WebhookPayloadPatch {
+		// each item is a full object, so the updates queued meanwhile, if any, supersede the failed ones
+		if len(pub.pending) == 0 {
+			pub.pending = []WebhookItem{failed[len(failed)-1]}
+		}
+		return
+	}
+	if pub.prevNRT == nil {
+		return
+	}
+	// the receiver may have missed some patches, and the updates queued meanwhile are patches against
+	// data it never got: restart from the latest full object, which is what they all add up to
+	last := failed[len(failed)-1]
+	if len(pub.pending) > 0 {
+		last = pub.pending[len(pub.pending)-1]
+	}
+	data, err := json.Marshal(pub.prevNRT)
+	if err != nil {
+		klog.Warningf("nrtupdater webhook: cannot resync the queued updates: %v", err)
+		pub.pending = nil
+		pub.prevNRT = nil
+		return
+	}
+	pub.pending = []WebhookItem{
+		{
+			Type:      WebhookPayloadObject,
+			Timestamp: last.Timestamp,
+			Data:      data,
+		},
+	}
+}
+
+func (pub *WebhookPublisher) flushOnTimer() {
+	pub.lock.Lock()
+	parent := pub.ctx
+	pub.lock.Unlock()
+	ctx, cancel := context.WithTimeout(parent, webhookRequestTimeout*time.Duration(pub.retries+1))
+	defer cancel()
+	err := pub.Flush(ctx)
+	if err != nil {
+		klog.Warningf("nrtupdater webhook: failed to send batched updates: %v", err)
+	}
+	pub.reportOutcome(err)
+}
+
+func (pub *WebhookPublisher) reportOutcome(err error) {
+	for {
+		select {
+		case pub.outcomes <- err:
+			return
+		default:
+		}
+		// full: make room dropping the oldest outcome, unless the consumer took it meanwhile
+		select {
+		case <-pub.outcomes:
+		default:
+		}
+	}
+}
+
+// makeItem must be called with the lock held.
+func (pub *WebhookPublisher) makeItem(nrt *v1alpha2.NodeResourceTopology) (WebhookItem, error) {
+	item := WebhookItem{
+		Type:      WebhookPayloadObject,
+		Timestamp: time.Now(),
+	}
+	if pub.payload == WebhookPayloadPatch && pub.prevNRT != nil {
+		patchInfo, _, err := MakeNRTPatch(pub.prevNRT, nrt)
+		if err != nil {
+			return item, err
+		}
+		item.Type = WebhookPayloadPatch
+		item.Data = patchInfo.Patch
+	} else {
+		data, err := json.Marshal(nrt)
+		if err != nil {
+			return item, err
+		}
+		item.Data = data
+	}
+	if pub.payload == WebhookPayloadPatch {
+		pub.prevNRT = nrt.DeepCopy()
+	}
+	return item, nil
+}
+
+func (pub *WebhookPublisher) send(ctx context.Context, msg WebhookMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	delay := pub.retryBase
+	for attempt := 0; ; attempt++ {
+		err = pub.post(ctx, body)
+		if err == nil || errors.Is(err, ErrWebhookRejected) || attempt >= pub.retries {
+			return err
+		}
+		klog.V(4).Infof("nrtupdater webhook: attempt %d failed, retrying in %v: %v", attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, webhookRetryMax)
+	}
+}
+
+// post returns ErrWebhookRejected if the request should not be retried.
+func (pub *WebhookPublisher) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pub.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookRejected, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := pub.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return fmt.Errorf("%w: %s", ErrWebhookRejected, resp.Status)
+}
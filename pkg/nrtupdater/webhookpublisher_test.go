@@ -0,0 +1,610 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtupdater
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sevents"
+)
+
+type webhookRecorder struct {
+	lock     sync.Mutex
+	messages []WebhookMessage
+	statuses []int // replied in order, then 200
+	requests int
+}
+
+func (wr *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+	wr.requests++
+	if len(wr.statuses) > 0 {
+		status := wr.statuses[0]
+		wr.statuses = wr.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	var msg WebhookMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	wr.messages = append(wr.messages, msg)
+}
+
+func (wr *webhookRecorder) Messages() []WebhookMessage {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+	return append([]WebhookMessage{}, wr.messages...)
+}
+
+func (wr *webhookRecorder) Requests() int {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+	return wr.requests
+}
+
+func TestWebhookPublisherPayload(t *testing.T) {
+	type testCase struct {
+		name          string
+		payload       string
+		expectedTypes []string
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:          "object",
+			payload:       WebhookPayloadObject,
+			expectedTypes: []string{WebhookPayloadObject, WebhookPayloadObject},
+		},
+		{
+			name:          "patch",
+			payload:       WebhookPayloadPatch,
+			expectedTypes: []string{WebhookPayloadObject, WebhookPayloadPatch},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			rec := &webhookRecorder{}
+			srv := httptest.NewServer(rec)
+			t.Cleanup(srv.Close)
+
+			nrtUpd := newWebhookUpdater(t, Args{Publisher: PublisherWebhook, WebhookURL: srv.URL, WebhookPayload: tcase.payload})
+			for _, zoneName := range []string{"zone-0", "zone-1"} {
+				err := nrtUpd.Update(context.Background(), MonitorInfo{Zones: v1alpha2.ZoneList{{Name: zoneName, Type: "node"}}})
+				if err != nil {
+					t.Fatalf("update failed: %v", err)
+				}
+			}
+
+			msgs := rec.Messages()
+			if len(msgs) != len(tcase.expectedTypes) {
+				t.Fatalf("unexpected messages: got %d expected %d", len(msgs), len(tcase.expectedTypes))
+			}
+			for idx, msg := range msgs {
+				if msg.Node != "test-node" || len(msg.Items) != 1 {
+					t.Fatalf("unexpected message %d: %+v", idx, msg)
+				}
+				if msg.Items[0].Type != tcase.expectedTypes[idx] {
+					t.Errorf("unexpected item %d type: got %q expected %q", idx, msg.Items[0].Type, tcase.expectedTypes[idx])
+				}
+			}
+			var nrt v1alpha2.NodeResourceTopology
+			err := json.Unmarshal(msgs[0].Items[0].Data, &nrt)
+			if err != nil {
+				t.Fatalf("failed to decode the object: %v", err)
+			}
+			checkPublishedNRT(t, &nrt, "zone-0")
+		})
+	}
+}
+
+func TestWebhookPublisherRetries(t *testing.T) {
+	type testCase struct {
+		name             string
+		statuses         []int
+		retries          int
+		expectedError    error
+		expectedRequests int
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:             "recovers",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			retries:          2,
+			expectedRequests: 3,
+		},
+		{
+			name:             "gives up",
+			statuses:         []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			retries:          1,
+			expectedError:    errors.New("any"),
+			expectedRequests: 2,
+		},
+		{
+			name:             "rejected",
+			statuses:         []int{http.StatusBadRequest},
+			retries:          3,
+			expectedError:    ErrWebhookRejected,
+			expectedRequests: 1,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			rec := &webhookRecorder{statuses: tcase.statuses}
+			srv := httptest.NewServer(rec)
+			t.Cleanup(srv.Close)
+
+			pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookRetries: tcase.retries})
+			if err != nil {
+				t.Fatalf("failed to create the publisher: %v", err)
+			}
+			pub.retryBase = time.Millisecond
+
+			err = pub.Publish(context.Background(), makeTestNRT("zone-0"))
+			if (err != nil) != (tcase.expectedError != nil) {
+				t.Fatalf("error mismatch: got %v expected %v", err, tcase.expectedError)
+			}
+			if errors.Is(tcase.expectedError, ErrWebhookRejected) && !errors.Is(err, ErrWebhookRejected) {
+				t.Errorf("expected rejection, got %v", err)
+			}
+			if got := rec.Requests(); got != tcase.expectedRequests {
+				t.Errorf("unexpected requests: got %d expected %d", got, tcase.expectedRequests)
+			}
+		})
+	}
+}
+
+func TestWebhookPublisherBatching(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookBatchSize: 3})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+
+	ctx := context.Background()
+	for idx, zoneName := range []string{"zone-0", "zone-1", "zone-2", "zone-3"} {
+		err = pub.Publish(ctx, makeTestNRT(zoneName))
+		if idx == 2 {
+			if err != nil {
+				t.Fatalf("publish of the full batch failed: %v", err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrPublishPending) {
+			t.Fatalf("expected the update to be queued, got %v", err)
+		}
+	}
+	msgs := rec.Messages()
+	if len(msgs) != 1 || len(msgs[0].Items) != 3 {
+		t.Fatalf("expected a single full batch, got %+v", msgs)
+	}
+
+	err = pub.Flush(ctx)
+	if err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	msgs = rec.Messages()
+	if len(msgs) != 2 || len(msgs[1].Items) != 1 {
+		t.Fatalf("expected the flushed partial batch, got %+v", msgs)
+	}
+
+	err = pub.Flush(ctx)
+	if err != nil {
+		t.Fatalf("empty flush failed: %v", err)
+	}
+	if got := rec.Requests(); got != 2 {
+		t.Errorf("unexpected requests after empty flush: %d", got)
+	}
+}
+
+func TestWebhookPublisherBatchInterval(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookBatchSize: 10, WebhookBatchInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	err = pub.Publish(context.Background(), makeTestNRT("zone-0"))
+	if !errors.Is(err, ErrPublishPending) {
+		t.Fatalf("expected the update to be queued, got %v", err)
+	}
+
+	select {
+	case err := <-pub.PublishOutcomes():
+		if err != nil {
+			t.Fatalf("batch send failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("partial batch not sent after the batch interval")
+	}
+	msgs := rec.Messages()
+	if len(msgs) != 1 || len(msgs[0].Items) != 1 {
+		t.Errorf("unexpected messages: %+v", msgs)
+	}
+}
+
+func TestWebhookPublisherFlushDoesNotBlockPublish(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookBatchSize: 10})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	ctx := context.Background()
+	_ = pub.Publish(ctx, makeTestNRT("zone-0"))
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- pub.Flush(ctx)
+	}()
+	<-received
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- pub.Publish(ctx, makeTestNRT("zone-1"))
+	}()
+	select {
+	case err := <-queued:
+		if !errors.Is(err, ErrPublishPending) {
+			t.Errorf("expected the update to be queued, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("publish blocked by the ongoing send")
+	}
+}
+
+func TestWebhookQueuedUpdatesNotReportedAsPublished(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	hb := &fakeHeartbeat{}
+	nrtUpd := newWebhookUpdater(t, Args{Publisher: PublisherWebhook, WebhookURL: srv.URL, WebhookBatchSize: 2}, WithHeartbeat(hb))
+
+	ctx := context.Background()
+	info := MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}}
+	err := nrtUpd.Update(ctx, info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if hb.beats != 0 || rec.Requests() != 0 {
+		t.Fatalf("queued update reported as published: beats=%d requests=%d", hb.beats, rec.Requests())
+	}
+
+	err = nrtUpd.Update(ctx, info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if hb.beats != 1 || rec.Requests() != 1 {
+		t.Errorf("delivered batch not reported as published: beats=%d requests=%d", hb.beats, rec.Requests())
+	}
+}
+
+func TestWebhookTimerFlushFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	rep := &fakeReporter{}
+	hb := &fakeHeartbeat{}
+	nrtUpd := newWebhookUpdater(t, Args{Publisher: PublisherWebhook, WebhookURL: srv.URL, WebhookBatchSize: 10, WebhookBatchInterval: time.Millisecond},
+		WithEventReporter(rep),
+		WithHeartbeat(hb),
+	)
+
+	ctx := context.Background()
+	info := MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}}
+	for idx := 0; idx < publishFailuresThreshold; idx++ {
+		err := nrtUpd.Update(ctx, info)
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		select {
+		case err := <-nrtUpd.publishOutcomes:
+			if err == nil {
+				t.Fatalf("timer flush unexpectedly succeeded")
+			}
+			nrtUpd.deferredPublishDone(ctx, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timer flush outcome not reported")
+		}
+	}
+	if nrtUpd.failureCount != publishFailuresThreshold {
+		t.Errorf("timer flush failures not tracked: got %d expected %d", nrtUpd.failureCount, publishFailuresThreshold)
+	}
+	if len(rep.reasons) != 1 || rep.reasons[0] != k8sevents.ReasonPublishFailed {
+		t.Errorf("expected exactly one %q event, got %v", k8sevents.ReasonPublishFailed, rep.reasons)
+	}
+	if hb.beats != 0 {
+		t.Errorf("unexpected beats after failed deliveries: %d", hb.beats)
+	}
+}
+
+func TestWebhookPublisherRequeueOnFailure(t *testing.T) {
+	rec := &webhookRecorder{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookBatchSize: 2})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+
+	ctx := context.Background()
+	_ = pub.Publish(ctx, makeTestNRT("zone-0"))
+	err = pub.Publish(ctx, makeTestNRT("zone-1"))
+	if err == nil {
+		t.Fatalf("publish unexpectedly succeeded")
+	}
+
+	err = pub.Flush(ctx)
+	if err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	msgs := rec.Messages()
+	if len(msgs) != 1 || len(msgs[0].Items) != 1 {
+		t.Fatalf("expected the latest object only, got %+v", msgs)
+	}
+	var nrt v1alpha2.NodeResourceTopology
+	err = json.Unmarshal(msgs[0].Items[0].Data, &nrt)
+	if err != nil {
+		t.Fatalf("failed to decode the item: %v", err)
+	}
+	checkPublishedNRT(t, &nrt, "zone-1")
+}
+
+func TestWebhookTimerFlushBoundContext(t *testing.T) {
+	received := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server notices the client went away only after the body is consumed
+		_, _ = io.Copy(io.Discard, r.Body)
+		received <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookBatchSize: 10, WebhookBatchInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pub.BindContext(ctx)
+
+	_ = pub.Publish(context.Background(), makeTestNRT("zone-0"))
+	<-received
+	cancel()
+
+	select {
+	case err := <-pub.PublishOutcomes():
+		if err == nil {
+			t.Fatalf("aborted batch reported as delivered")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timer flush not aborted with the bound context")
+	}
+	pub.lock.Lock()
+	defer pub.lock.Unlock()
+	if len(pub.pending) != 1 {
+		t.Errorf("aborted batch not queued again: %+v", pub.pending)
+	}
+}
+
+func TestWebhookPublisherMutualTLS(t *testing.T) {
+	certDir := t.TempDir()
+	certFile, keyFile := makeTestCertificate(t, certDir)
+
+	clientCert, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("failed to read the client certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(clientCert)
+
+	rec := &webhookRecorder{}
+	srv := httptest.NewUnstartedServer(rec)
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(certDir, "ca.crt")
+	err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatalf("failed to write the CA file: %v", err)
+	}
+
+	pub, err := NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookCAFile: caFile})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	err = pub.Publish(context.Background(), makeTestNRT("zone-0"))
+	if err == nil {
+		t.Fatalf("publish succeeded without client certificate")
+	}
+
+	pub, err = NewWebhookPublisher(Args{WebhookURL: srv.URL, WebhookCAFile: caFile, WebhookCertFile: certFile, WebhookKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	err = pub.Publish(context.Background(), makeTestNRT("zone-0"))
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if len(rec.Messages()) != 1 {
+		t.Errorf("unexpected messages: %+v", rec.Messages())
+	}
+}
+
+func TestWebhookMirrorPublisher(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	cli := fake.NewSimpleClientset()
+	args := Args{Hostname: "test-node", WebhookURL: srv.URL}
+	mpub, err := NewMirrorPublisher(args)
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, cli, args, TMConfig{}, WithMirrorPublisher(mpub))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+
+	err = nrtUpd.Update(context.Background(), MonitorInfo{Zones: v1alpha2.ZoneList{{Name: "zone-0", Type: "node"}}})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if _, err := cli.Tracker().Get(nrtResource, "", "test-node"); err != nil {
+		t.Errorf("NRT object not written: %v", err)
+	}
+	if len(rec.Messages()) != 1 {
+		t.Errorf("webhook not notified: %+v", rec.Messages())
+	}
+}
+
+func TestWebhookMirrorOutcomesConsumed(t *testing.T) {
+	args := Args{Hostname: "test-node", WebhookURL: "http://127.0.0.1:0"}
+	mpub, err := NewMirrorPublisher(args)
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, fake.NewSimpleClientset(), args, TMConfig{}, WithMirrorPublisher(mpub))
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+	webhook := mpub.(*WebhookPublisher)
+	for idx := 0; idx < webhookOutcomesBuffer; idx++ {
+		webhook.reportOutcome(errors.New("failed"))
+	}
+
+	infoChannel := make(chan MonitorInfo)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		nrtUpd.Run(context.Background(), infoChannel, nil)
+	}()
+	defer func() {
+		close(infoChannel)
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(webhook.outcomes) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("mirror outcomes not consumed: %d left", len(webhook.outcomes))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newWebhookUpdater(t *testing.T, args Args, options ...func(*NRTUpdater)) *NRTUpdater {
+	t.Helper()
+	args.Hostname = "test-node"
+	pub, err := NewPublisher(args, nil)
+	if err != nil {
+		t.Fatalf("failed to create the publisher: %v", err)
+	}
+	nrtUpd, err := NewNRTUpdater(&DisabledNodeGetter{}, fake.NewSimpleClientset(), args, TMConfig{}, append(options, WithPublisher(pub))...)
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+	return nrtUpd
+}
+
+func makeTestNRT(zoneName string) *v1alpha2.NodeResourceTopology {
+	nrt := v1alpha2.NodeResourceTopology{Zones: v1alpha2.ZoneList{{Name: zoneName, Type: "node"}}}
+	nrt.Kind = "NodeResourceTopology"
+	nrt.Name = "test-node"
+	return &nrt
+}
+
+// makeTestCertificate writes a self-signed client certificate and its key, returning their paths.
+func makeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "rte-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
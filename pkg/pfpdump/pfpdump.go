@@ -19,12 +19,13 @@ package pfpdump
 import (
 	"context"
 	"encoding/json"
-	"os"
 	"path/filepath"
 
 	"k8s.io/klog/v2"
 
 	"github.com/k8stopologyawareschedwg/podfingerprint"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/dump"
 )
 
 type Handle struct {
@@ -67,21 +68,5 @@ func ToFile(st podfingerprint.Status, dir, file string) error {
 		return err
 	}
 
-	dst, err := os.CreateTemp(dir, "__"+file)
-	if err != nil {
-		return err
-	}
-	defer os.Remove(dst.Name()) // either way, we need to get rid of this
-
-	_, err = dst.Write(data)
-	if err != nil {
-		return err
-	}
-
-	err = dst.Close()
-	if err != nil {
-		return err
-	}
-
-	return os.Rename(dst.Name(), filepath.Join(dir, file))
+	return dump.ReplaceFile(dir, file, data, 0600)
 }
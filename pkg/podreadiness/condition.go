@@ -1,6 +1,7 @@
 package podreadiness
 
 import (
+	"context"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -22,6 +23,24 @@ func SetCondition(condChan chan<- v1.PodCondition, condType RTEConditionType, co
 	if condChan == nil {
 		return
 	}
+	condChan <- newCondition(condType, condStatus)
+}
+
+// SetConditionWithContext is like SetCondition, but gives up if ctx is done before the condition is consumed.
+// Returns false if the condition was not sent.
+func SetConditionWithContext(ctx context.Context, condChan chan<- v1.PodCondition, condType RTEConditionType, condStatus v1.ConditionStatus) bool {
+	if condChan == nil {
+		return true
+	}
+	select {
+	case condChan <- newCondition(condType, condStatus):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func newCondition(condType RTEConditionType, condStatus v1.ConditionStatus) v1.PodCondition {
 	cond := newConditionTemplate(condType, condStatus)
 	if condStatus == v1.ConditionFalse {
 		switch condType {
@@ -33,7 +52,23 @@ func SetCondition(condChan chan<- v1.PodCondition, condType RTEConditionType, co
 			cond.Message = "failed to update noderesourcetopology object"
 		}
 	}
-	condChan <- cond
+	return cond
+}
+
+// SetShutdownCondition reports the given condition as False because the exporter is shutting down.
+func SetShutdownCondition(condChan chan<- v1.PodCondition, condType RTEConditionType) {
+	if condChan == nil {
+		return
+	}
+	condChan <- NewShutdownCondition(condType)
+}
+
+// NewShutdownCondition returns the given condition as False because the exporter is shutting down.
+func NewShutdownCondition(condType RTEConditionType) v1.PodCondition {
+	cond := newConditionTemplate(condType, v1.ConditionFalse)
+	cond.Reason = "Shutdown"
+	cond.Message = "the exporter is shutting down"
+	return cond
 }
 
 func newConditionTemplate(condType RTEConditionType, status v1.ConditionStatus) (condition v1.PodCondition) {
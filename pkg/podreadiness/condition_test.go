@@ -56,3 +56,22 @@ func TestSetCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestSetShutdownCondition(t *testing.T) {
+	c := make(chan v1.PodCondition)
+	for _, cType := range []RTEConditionType{PodresourcesFetched, NodeTopologyUpdated} {
+		t.Run(string(cType), func(t *testing.T) {
+			go SetShutdownCondition(c, cType)
+			cond := <-c
+			if cond.Status != v1.ConditionFalse {
+				t.Errorf("expected status %q, got %q", v1.ConditionFalse, cond.Status)
+			}
+			if RTEConditionType(cond.Type) != cType {
+				t.Errorf("expected type %q, got %q", cType, cond.Type)
+			}
+			if cond.Reason != "Shutdown" {
+				t.Errorf("expected reason %q, got %q", "Shutdown", cond.Reason)
+			}
+		})
+	}
+}
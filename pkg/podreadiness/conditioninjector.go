@@ -76,10 +76,14 @@ func (ci *ConditionInjector) Inject(ctx context.Context, cond v1.PodCondition) e
 	return nil
 }
 
+// Run injects the conditions received on condChan until the context is cancelled or condChan is closed.
 func (ci *ConditionInjector) Run(ctx context.Context, condChan <-chan v1.PodCondition) {
 	for {
 		select {
-		case cond := <-condChan:
+		case cond, ok := <-condChan:
+			if !ok {
+				return
+			}
 			err := ci.Inject(ctx, cond)
 			if err != nil {
 				klog.Errorf("failed to update pod status with condition: %v", cond)
@@ -18,27 +18,22 @@ package terminalpods
 
 import (
 	"context"
-	"fmt"
-	"time"
 
 	"google.golang.org/grpc"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	informerscorve1 "k8s.io/client-go/informers/core/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
-)
 
-const terminalFieldSelector = "status.phase==Failed,status.phase==Succeeded"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
+)
 
 type filteringClient struct {
 	debug    bool
 	cli      podresourcesapi.PodResourcesListerClient
-	kcli     kubernetes.Interface
 	informer informerscorve1.PodInformer
 }
 
@@ -53,7 +48,7 @@ func (fc *filteringClient) List(ctx context.Context, in *podresourcesapi.ListPod
 		return resp, err
 	}
 
-	FilterFrom(resp, pods)
+	FilterFrom(resp, TerminalPods(pods))
 	return resp, nil
 }
 
@@ -65,30 +60,34 @@ func (fc *filteringClient) Get(ctx context.Context, in *podresourcesapi.GetPodRe
 	return fc.cli.Get(ctx, in, opts...) // TODO: not needed, but we should implement filtering for consistency
 }
 
-func NewFromLister(ctx context.Context, cli podresourcesapi.PodResourcesListerClient, kcli kubernetes.Interface, resyncPeriod time.Duration, debug bool) (podresourcesapi.PodResourcesListerClient, error) {
-	klog.V(2).Infof("terminalpods: resyncPeriod: %v", resyncPeriod)
-	tweakFunc := func(opts *metav1.ListOptions) {
-		// A pod is in a terminal state if .status.phase in (Failed, Succeeded) is true.
-		opts.FieldSelector = terminalFieldSelector
-	}
-	factory := informers.NewSharedInformerFactoryWithOptions(kcli, resyncPeriod, informers.WithTweakListOptions(tweakFunc))
+// NewFromLister filters the terminal pods using the pods known by the given informer factory.
+// The factory is expected to be node-scoped, see k8shelpers.NewNodeScopedInformerFactory.
+func NewFromLister(ctx context.Context, cli podresourcesapi.PodResourcesListerClient, factory informers.SharedInformerFactory, debug bool) (podresourcesapi.PodResourcesListerClient, error) {
 	podInformer := factory.Core().V1().Pods()
-	factory.Start(ctx.Done())
-	synced := factory.WaitForCacheSync(ctx.Done())
-	for v, ok := range synced {
-		if !ok {
-			return nil, fmt.Errorf("caches failed to sync: %v", v)
-		}
+	podInformer.Informer() // register the informer before starting the factory
+	err := k8shelpers.StartAndSync(ctx, factory)
+	if err != nil {
+		return nil, err
 	}
 	klog.Infof("terminalpods: ready")
 	return &filteringClient{
 		debug:    debug,
 		cli:      cli,
-		kcli:     kcli,
 		informer: podInformer,
 	}, nil
 }
 
+// TerminalPods returns the pods in a terminal state: .status.phase in (Failed, Succeeded).
+func TerminalPods(pods []*corev1.Pod) []*corev1.Pod {
+	var terminal []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+			terminal = append(terminal, pod)
+		}
+	}
+	return terminal
+}
+
 func FilterFrom(resp *podresourcesapi.ListPodResourcesResponse, pods []*corev1.Pod) {
 	var filterResp []*podresourcesapi.PodResources
 	podres := resp.GetPodResources()
@@ -128,3 +128,33 @@ func TestFilterFrom(t *testing.T) {
 		}
 	}
 }
+
+func TestTerminalPods(t *testing.T) {
+	pods := []*v1.Pod{
+		makePodWithPhase("podA", v1.PodRunning),
+		makePodWithPhase("podB", v1.PodSucceeded),
+		makePodWithPhase("podC", v1.PodPending),
+		makePodWithPhase("podD", v1.PodFailed),
+	}
+	got := TerminalPods(pods)
+	var names []string
+	for _, pod := range got {
+		names = append(names, pod.Name)
+	}
+	expected := []string{"podB", "podD"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected terminal pods: %s", diff)
+	}
+}
+
+func makePodWithPhase(name string, phase v1.PodPhase) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ns",
+		},
+		Status: v1.PodStatus{
+			Phase: phase,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traced
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"k8s.io/klog/v2"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/tracing"
+)
+
+const (
+	// LayerKubelet identifies the client talking to the kubelet, below all the middlewares
+	LayerKubelet = "kubelet"
+
+	rpcService = "v1.PodResourcesLister"
+)
+
+// tracingClient traces the calls through the wrapped layer. Wrapping each middleware,
+// the spans nest following the middleware chain, so the time spent in each layer stands out.
+type tracingClient struct {
+	cli   podresourcesapi.PodResourcesListerClient
+	layer string
+}
+
+func (tc *tracingClient) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	name := "podresources/" + method
+	if tc.layer != LayerKubelet {
+		name = "podresources/" + tc.layer + "/" + method
+	}
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(spanKind(tc.layer)),
+		trace.WithAttributes(
+			attribute.String(tracing.AttrRPCSystem, "grpc"),
+			attribute.String(tracing.AttrRPCService, rpcService),
+			attribute.String(tracing.AttrRPCMethod, method),
+			attribute.String(tracing.AttrLayer, tc.layer),
+		),
+	)
+}
+
+func (tc *tracingClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	ctx, span := tc.start(ctx, "List")
+	defer span.End()
+	resp, err := tc.cli.List(ctx, in, opts...)
+	tracing.SetError(span, err)
+	span.SetAttributes(attribute.Int(tracing.AttrPodCount, len(resp.GetPodResources())))
+	return resp, err
+}
+
+func (tc *tracingClient) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	ctx, span := tc.start(ctx, "GetAllocatableResources")
+	defer span.End()
+	resp, err := tc.cli.GetAllocatableResources(ctx, in, opts...)
+	tracing.SetError(span, err)
+	return resp, err
+}
+
+func (tc *tracingClient) Get(ctx context.Context, in *podresourcesapi.GetPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.GetPodResourcesResponse, error) {
+	ctx, span := tc.start(ctx, "Get")
+	defer span.End()
+	resp, err := tc.cli.Get(ctx, in, opts...)
+	tracing.SetError(span, err)
+	return resp, err
+}
+
+func spanKind(layer string) trace.SpanKind {
+	if layer == LayerKubelet {
+		return trace.SpanKindClient
+	}
+	return trace.SpanKindInternal
+}
+
+// NewFromLister traces the calls to the given client, identified as the given layer.
+func NewFromLister(cli podresourcesapi.PodResourcesListerClient, layer string) podresourcesapi.PodResourcesListerClient {
+	klog.V(4).Infof("traced: tracing podresources layer %q", layer)
+	return &tracingClient{
+		cli:   cli,
+		layer: layer,
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traced
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+type fakeLister struct {
+	podresourcesapi.PodResourcesListerClient
+	err error
+}
+
+func (fl *fakeLister) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	if fl.err != nil {
+		return nil, fl.err
+	}
+	return &podresourcesapi.ListPodResourcesResponse{
+		PodResources: []*podresourcesapi.PodResources{
+			{Name: "pod-a", Namespace: "ns"},
+			{Name: "pod-b", Namespace: "ns"},
+		},
+	}, nil
+}
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	prev := otel.GetTracerProvider()
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func TestNestedLayers(t *testing.T) {
+	sr := setupRecorder(t)
+
+	cli := NewFromLister(&fakeLister{}, LayerKubelet)
+	cli = NewFromLister(cli, "podexclude")
+	resp, err := cli.List(context.Background(), &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(resp.GetPodResources()) != 2 {
+		t.Errorf("response altered: %v", resp)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	inner, outer := spans[0], spans[1]
+	if inner.Name() != "podresources/List" || outer.Name() != "podresources/podexclude/List" {
+		t.Errorf("unexpected span names: %q %q", inner.Name(), outer.Name())
+	}
+	if inner.Parent().SpanID() != outer.SpanContext().SpanID() {
+		t.Errorf("kubelet call not nested in the middleware span")
+	}
+	for _, kv := range inner.Attributes() {
+		if kv.Key == "rte.podresources.pods" && kv.Value.AsInt64() != 2 {
+			t.Errorf("unexpected pod count: %v", kv.Value.AsInt64())
+		}
+	}
+}
+
+func TestFailedCall(t *testing.T) {
+	sr := setupRecorder(t)
+
+	cli := NewFromLister(&fakeLister{err: errors.New("fake failure")}, LayerKubelet)
+	_, err := cli.List(context.Background(), &podresourcesapi.ListPodResourcesRequest{})
+	if err == nil {
+		t.Fatalf("list unexpectedly succeeded")
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("failure not recorded: %v", spans[0].Status())
+	}
+}
@@ -14,6 +14,8 @@ limitations under the License.
 package ratelimiter
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"go.uber.org/ratelimit"
@@ -41,8 +43,8 @@ type RateLimitedEventSource struct {
 
 	doneSenderCh   chan struct{}
 	doneReceiverCh chan struct{}
-	stopSenderCh   chan struct{}
-	stopReceiverCh chan struct{}
+	stopCh         chan struct{}
+	stopOnce       sync.Once
 }
 
 func NewRateLimitedEventSource(es notification.EventSource, maxEventsPerTimeUnit uint64, timeUnit time.Duration) (*RateLimitedEventSource, error) {
@@ -52,10 +54,9 @@ func NewRateLimitedEventSource(es notification.EventSource, maxEventsPerTimeUnit
 		bufferCh: make(chan notification.Event, bufferSize),
 		outCh:    make(chan notification.Event),
 
-		doneSenderCh:   make(chan struct{}, 1),
-		doneReceiverCh: make(chan struct{}, 1),
-		stopSenderCh:   make(chan struct{}),
-		stopReceiverCh: make(chan struct{}),
+		doneSenderCh:   make(chan struct{}),
+		doneReceiverCh: make(chan struct{}),
+		stopCh:         make(chan struct{}),
 	}
 
 	options := ratelimit.Per(timeUnit)
@@ -68,9 +69,9 @@ func (rles *RateLimitedEventSource) Events() <-chan notification.Event {
 	return rles.outCh
 }
 
-func (rles *RateLimitedEventSource) Run() {
-	rles.run()
-	rles.es.Run()
+func (rles *RateLimitedEventSource) Run(ctx context.Context) {
+	rles.run(ctx)
+	rles.es.Run(ctx)
 }
 
 func (rles *RateLimitedEventSource) Stop() {
@@ -95,9 +96,9 @@ func (rles *RateLimitedEventSource) Close() {
 // to block on writing an event while the other one delivers events
 // at the configured rate.
 // see: receiver and sender functions for more info
-func (rles *RateLimitedEventSource) run() {
-	go rles.sender()
-	go rles.receiver()
+func (rles *RateLimitedEventSource) run(ctx context.Context) {
+	go rles.sender(ctx)
+	go rles.receiver(ctx)
 }
 
 // receiver read from the input channel and move the event to bufferCh as fast as possible
@@ -105,7 +106,8 @@ func (rles *RateLimitedEventSource) run() {
 // decorated EventSource is blocked trying to write a new event in the "input" channel.
 // Also the write in bufferCh is done so if it is full the operation silently "fails"
 // instead of block
-func (rles *RateLimitedEventSource) receiver() {
+func (rles *RateLimitedEventSource) receiver(ctx context.Context) {
+	defer close(rles.doneReceiverCh)
 	for {
 		select {
 		case incomingEvent := <-rles.inCh:
@@ -113,27 +115,56 @@ func (rles *RateLimitedEventSource) receiver() {
 			case rles.bufferCh <- incomingEvent:
 			default:
 			}
-		case <-rles.stopReceiverCh:
-			rles.doneReceiverCh <- struct{}{}
+		case <-rles.stopCh:
+			return
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
 // sender read events from the bufferCh and write it in the "output" channel at the configured rate.
-func (rles *RateLimitedEventSource) sender() {
+// Once stopped, it delivers no more events, even if some are still buffered.
+func (rles *RateLimitedEventSource) sender(ctx context.Context) {
+	defer close(rles.doneSenderCh)
 	for {
+		if rles.stopping(ctx) {
+			return
+		}
 		select {
 		case event := <-rles.bufferCh:
 			rles.rt.Take()
-			rles.outCh <- event
-		case <-rles.stopSenderCh:
-			rles.doneSenderCh <- struct{}{}
+			if rles.stopping(ctx) {
+				return
+			}
+			select {
+			case rles.outCh <- event:
+			case <-rles.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		case <-rles.stopCh:
+			return
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// stopping tells if the event source was stopped, so the sender can check it before
+// waiting on the other channels: select picks among the ready cases at random.
+func (rles *RateLimitedEventSource) stopping(ctx context.Context) bool {
+	select {
+	case <-rles.stopCh:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 // wait stops the caller until the EventSource is exhausted
 func (rles *RateLimitedEventSource) wait() {
 	<-rles.doneReceiverCh
@@ -141,6 +172,7 @@ func (rles *RateLimitedEventSource) wait() {
 }
 
 func (rles *RateLimitedEventSource) stop() {
-	rles.stopReceiverCh <- struct{}{}
-	rles.stopSenderCh <- struct{}{}
+	rles.stopOnce.Do(func() {
+		close(rles.stopCh)
+	})
 }
@@ -14,6 +14,7 @@ limitations under the License.
 package ratelimiter
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -45,7 +46,7 @@ func (es *DummyEventSource) Stop() {
 	es.stopChan <- struct{}{}
 }
 
-func (es *DummyEventSource) Run() {
+func (es *DummyEventSource) Run(ctx context.Context) {
 
 	ticker := time.NewTicker(es.PeriodicEvents)
 
@@ -54,6 +55,8 @@ func (es *DummyEventSource) Run() {
 		select {
 		case <-es.stopChan:
 			keepLooping = false
+		case <-ctx.Done():
+			keepLooping = false
 		case <-ticker.C:
 			es.Ech <- notification.Event{Timestamp: time.Now()}
 		}
@@ -86,11 +89,15 @@ func TestLimitedRateLimit(t *testing.T) {
 	}
 
 	// Launch both EventSource and a receiver
-	go sut.Run()
+	go sut.Run(context.Background())
 
 	done := make(chan struct{})
+	finished := make(chan struct{})
 	var results []result
-	go receiver(t, sut.Events(), done, &results)
+	go func() {
+		defer close(finished)
+		receiver(t, sut.Events(), done, &results)
+	}()
 
 	time.Sleep(1 * time.Second)
 
@@ -98,6 +105,8 @@ func TestLimitedRateLimit(t *testing.T) {
 	sut.Stop()
 	sut.Wait()
 	done <- struct{}{}
+	// the receiver owns the results until it exits
+	<-finished
 
 	// Check results
 	if len(results) == 0 {
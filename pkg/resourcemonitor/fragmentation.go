@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemonitor
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+const (
+	// AttributeLargestFreePrefix prefixes the resource name in the attribute reporting the largest
+	// amount of the resource available in a single zone.
+	AttributeLargestFreePrefix = "largestZoneFree."
+	// AttributeStrandedRatioPrefix prefixes the resource name in the attribute reporting the fraction
+	// of the available resource which can't be used by a request fitting a single zone.
+	AttributeStrandedRatioPrefix = "strandedRatio."
+)
+
+// Fragmentation describes how the available amount of a resource is spread across the zones.
+type Fragmentation struct {
+	Resource    string
+	LargestFree int64
+	TotalFree   int64
+}
+
+// StrandedRatio is the fraction of the available resource outside the zone with the largest
+// available amount, which is thus unusable by a request needing the largest single-zone block.
+// 0 means no fragmentation, values approaching 1 mean the resource is scattered across many zones.
+func (fr Fragmentation) StrandedRatio() float64 {
+	if fr.TotalFree == 0 {
+		return 0
+	}
+	return 1.0 - float64(fr.LargestFree)/float64(fr.TotalFree)
+}
+
+// ComputeFragmentation computes the fragmentation indicators for each resource reported in the zones.
+// The result is sorted by resource name.
+func ComputeFragmentation(zones v1alpha2.ZoneList) []Fragmentation {
+	byName := make(map[string]*Fragmentation)
+	for _, zone := range zones {
+		for _, res := range zone.Resources {
+			fr, ok := byName[res.Name]
+			if !ok {
+				fr = &Fragmentation{Resource: res.Name}
+				byName[res.Name] = fr
+			}
+			avail := res.Available.Value()
+			fr.TotalFree += avail
+			if avail > fr.LargestFree {
+				fr.LargestFree = avail
+			}
+		}
+	}
+	frags := make([]Fragmentation, 0, len(byName))
+	for _, fr := range byName {
+		frags = append(frags, *fr)
+	}
+	sort.Slice(frags, func(i, j int) bool {
+		return frags[i].Resource < frags[j].Resource
+	})
+	return frags
+}
+
+// FragmentationAttributes represents the fragmentation indicators as NRT attributes.
+func FragmentationAttributes(frags []Fragmentation) v1alpha2.AttributeList {
+	attrs := make(v1alpha2.AttributeList, 0, 2*len(frags))
+	for _, fr := range frags {
+		attrs = append(attrs, v1alpha2.AttributeInfo{
+			Name:  AttributeLargestFreePrefix + fr.Resource,
+			Value: strconv.FormatInt(fr.LargestFree, 10),
+		})
+		attrs = append(attrs, v1alpha2.AttributeInfo{
+			Name:  AttributeStrandedRatioPrefix + fr.Resource,
+			Value: strconv.FormatFloat(fr.StrandedRatio(), 'f', 3, 64),
+		})
+	}
+	return attrs
+}
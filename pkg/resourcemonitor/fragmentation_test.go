@@ -0,0 +1,99 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemonitor
+
+import (
+	"testing"
+
+	cmp "github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+func makeFragZone(name string, avail map[string]string) v1alpha2.Zone {
+	zone := v1alpha2.Zone{
+		Name: name,
+		Type: "Node",
+	}
+	for resName, qty := range avail {
+		zone.Resources = append(zone.Resources, v1alpha2.ResourceInfo{
+			Name:      resName,
+			Available: resource.MustParse(qty),
+		})
+	}
+	return zone
+}
+
+func TestComputeFragmentation(t *testing.T) {
+	type testCase struct {
+		name          string
+		zones         v1alpha2.ZoneList
+		expected      []Fragmentation
+		expectedAttrs v1alpha2.AttributeList
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name:          "no zones",
+			expected:      []Fragmentation{},
+			expectedAttrs: v1alpha2.AttributeList{},
+		},
+		{
+			name: "single zone",
+			zones: v1alpha2.ZoneList{
+				makeFragZone("node-0", map[string]string{"cpu": "16"}),
+			},
+			expected: []Fragmentation{
+				{Resource: "cpu", LargestFree: 16, TotalFree: 16},
+			},
+			expectedAttrs: v1alpha2.AttributeList{
+				{Name: "largestZoneFree.cpu", Value: "16"},
+				{Name: "strandedRatio.cpu", Value: "0.000"},
+			},
+		},
+		{
+			name: "free resources scattered",
+			zones: v1alpha2.ZoneList{
+				makeFragZone("node-0", map[string]string{"cpu": "6", "fake.io/gpu": "0"}),
+				makeFragZone("node-1", map[string]string{"cpu": "10", "fake.io/gpu": "0"}),
+				makeFragZone("node-2", map[string]string{"cpu": "4"}),
+			},
+			expected: []Fragmentation{
+				{Resource: "cpu", LargestFree: 10, TotalFree: 20},
+				{Resource: "fake.io/gpu", LargestFree: 0, TotalFree: 0},
+			},
+			expectedAttrs: v1alpha2.AttributeList{
+				{Name: "largestZoneFree.cpu", Value: "10"},
+				{Name: "strandedRatio.cpu", Value: "0.500"},
+				{Name: "largestZoneFree.fake.io/gpu", Value: "0"},
+				{Name: "strandedRatio.fake.io/gpu", Value: "0.000"},
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := ComputeFragmentation(tcase.zones)
+			if diff := cmp.Diff(tcase.expected, got); diff != "" {
+				t.Errorf("unexpected fragmentation: %s", diff)
+			}
+			gotAttrs := FragmentationAttributes(got)
+			if diff := cmp.Diff(tcase.expectedAttrs, gotAttrs); diff != "" {
+				t.Errorf("unexpected attributes: %s", diff)
+			}
+		})
+	}
+}
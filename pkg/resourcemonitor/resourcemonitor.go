@@ -42,6 +42,10 @@ import (
 	topologyv1alpha2 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	"github.com/k8stopologyawareschedwg/podfingerprint"
 
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/debugstate"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/health"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sevents"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	podresfilter "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/filter"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres/filter/numalocality"
@@ -75,26 +79,46 @@ type Args struct {
 	PodSetFingerprintStatusFile string          `json:"podSetFingerprintStatusFile,omitempty"`
 	PodExclude                  podexclude.List `json:"podExclude,omitempty"`
 	ExcludeTerminalPods         bool            `json:"excludeTerminalPods,omitempty"`
+	// PodAllocationMetrics enables the per-container exclusive allocation metrics.
+	PodAllocationMetrics bool `json:"podAllocationMetrics,omitempty"`
+	// PodAllocationMetricsMaxSeries limits the cardinality of the per-container metrics. 0 means the default.
+	PodAllocationMetricsMaxSeries int `json:"podAllocationMetricsMaxSeries,omitempty"`
+	// Fragmentation enables the computation of the NUMA fragmentation indicators.
+	Fragmentation bool `json:"fragmentation,omitempty"`
 }
 
 func (args Args) Clone() Args {
 	return Args{
-		Namespace:                   args.Namespace,
-		SysfsRoot:                   args.SysfsRoot,
-		ResourceExclude:             args.ResourceExclude.Clone(),
-		RefreshNodeResources:        args.RefreshNodeResources,
-		PodSetFingerprint:           args.PodSetFingerprint,
-		PodSetFingerprintMethod:     args.PodSetFingerprintMethod,
-		ExposeTiming:                args.ExposeTiming,
-		PodSetFingerprintStatusFile: args.PodSetFingerprintStatusFile,
-		PodExclude:                  args.PodExclude.Clone(),
-		ExcludeTerminalPods:         args.ExcludeTerminalPods,
+		Namespace:                     args.Namespace,
+		SysfsRoot:                     args.SysfsRoot,
+		ResourceExclude:               args.ResourceExclude.Clone(),
+		RefreshNodeResources:          args.RefreshNodeResources,
+		PodSetFingerprint:             args.PodSetFingerprint,
+		PodSetFingerprintMethod:       args.PodSetFingerprintMethod,
+		ExposeTiming:                  args.ExposeTiming,
+		PodSetFingerprintStatusFile:   args.PodSetFingerprintStatusFile,
+		PodExclude:                    args.PodExclude.Clone(),
+		ExcludeTerminalPods:           args.ExcludeTerminalPods,
+		PodAllocationMetrics:          args.PodAllocationMetrics,
+		PodAllocationMetricsMaxSeries: args.PodAllocationMetricsMaxSeries,
+		Fragmentation:                 args.Fragmentation,
 	}
 }
 
 type Handle struct {
 	PodResCli podresourcesapi.PodResourcesListerClient
 	K8SCli    kubernetes.Interface
+	// Informers is expected to be node-scoped, see k8shelpers.NewNodeScopedInformerFactory.
+	// If nil, a private node-scoped factory is created from K8SCli when needed.
+	Informers informers.SharedInformerFactory
+	// Events receives the anomalies found while scanning. If nil, they are only logged.
+	Events k8sevents.Reporter
+	// Metrics records the metrics of this exporter instance. If nil, metrics.Default() is used.
+	Metrics *metrics.Metrics
+	// Health receives the outcome of the pipeline stages. If nil, the outcomes are discarded.
+	Health *health.Tracker
+	// Debug receives the snapshots of the intermediate data. If nil, nothing is recorded.
+	Debug *debugstate.State
 }
 
 type ScanResponse struct {
@@ -122,7 +146,7 @@ func (sr ScanResponse) SortedZones() v1alpha2.ZoneList {
 }
 
 type ResourceMonitor interface {
-	Scan(excludeList ResourceExclude) (ScanResponse, error)
+	Scan(ctx context.Context, excludeList ResourceExclude) (ScanResponse, error)
 }
 
 // ToMapSet keeps the original keys, but replaces values with set.String types
@@ -175,18 +199,34 @@ type resourceMonitor struct {
 	args              Args
 	podResCli         podresourcesapi.PodResourcesListerClient
 	k8sCli            kubernetes.Interface
+	informers         informers.SharedInformerFactory
 	topo              *ghwtopology.Info
 	coreIDToNodeIDMap map[int]int
 	nodeCapacity      perNUMAResourceCounter
 	nodeAllocatable   perNUMAResourceCounter
+	events            k8sevents.Reporter
+	metrics           *metrics.Metrics
+	health            *health.Tracker
+	debug             *debugstate.State
 }
 
 func NewResourceMonitor(hnd Handle, args Args, options ...func(*resourceMonitor)) (*resourceMonitor, error) {
 	rm := &resourceMonitor{
 		podResCli: hnd.PodResCli,
 		k8sCli:    hnd.K8SCli,
+		informers: hnd.Informers,
+		events:    hnd.Events,
+		metrics:   hnd.Metrics,
+		health:    hnd.Health,
+		debug:     hnd.Debug,
 		args:      args,
 	}
+	if rm.events == nil {
+		rm.events = k8sevents.DisabledReporter{}
+	}
+	if rm.metrics == nil {
+		rm.metrics = metrics.Default()
+	}
 	for _, opt := range options {
 		opt(rm)
 	}
@@ -221,7 +261,10 @@ func NewResourceMonitor(hnd Handle, args Args, options ...func(*resourceMonitor)
 		klog.Infof("resmon: getting node resources once")
 	} else {
 		klog.Infof("resmon: tracking node resources")
-		if err := addNodeInformerEvent(rm.k8sCli, cache.ResourceEventHandlerFuncs{UpdateFunc: rm.resUpdated}); err != nil {
+		if rm.informers == nil {
+			rm.informers = k8shelpers.NewNodeScopedInformerFactory(rm.k8sCli, rm.nodeName, 0)
+		}
+		if err := addNodeInformerEvent(rm.informers, cache.ResourceEventHandlerFuncs{UpdateFunc: rm.resUpdated}); err != nil {
 			return nil, err
 		}
 	}
@@ -252,14 +295,19 @@ func WithNodeName(name string) func(*resourceMonitor) {
 	}
 }
 
-func (rm *resourceMonitor) Scan(excludeList ResourceExclude) (ScanResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultPodResourcesTimeout)
+func (rm *resourceMonitor) Scan(ctx context.Context, excludeList ResourceExclude) (ScanResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultPodResourcesTimeout)
 	defer cancel()
+	tsBegin := time.Now()
 	resp, err := rm.podResCli.List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	rm.metrics.ObservePodResourcesAPICallDuration("list", time.Since(tsBegin))
 	if err != nil {
-		metrics.UpdatePodResourceApiCallsFailuresMetric("list")
+		rm.metrics.UpdatePodResourceApiCallsFailuresMetric("list")
+		rm.health.MarkFailure(health.StagePodResources, err)
 		return ScanResponse{}, err
 	}
+	rm.health.MarkSuccess(health.StagePodResources)
+	rm.debug.Record(debugstate.KeyPodResourcesList, resp)
 
 	respPodRes := resp.GetPodResources()
 	klog.V(6).Infof("resmon: podresources list: %s", collectPodsFromPodResources(respPodRes))
@@ -288,11 +336,16 @@ func (rm *resourceMonitor) Scan(excludeList ResourceExclude) (ScanResponse, erro
 		klog.V(6).Infof("resmon: pfp: %s", st.Repr())
 
 		podfingerprint.MarkCompleted(st)
+		rm.debug.Record(debugstate.KeyPodFingerprint, st)
 	}
 
 	allDevs := GetAllContainerDevices(respPodRes, rm.args.Namespace, rm.coreIDToNodeIDMap)
 	allocated := ContainerDevicesToPerNUMAResourceCounters(allDevs)
 
+	if rm.args.PodAllocationMetrics {
+		rm.metrics.UpdatePodAllocationsMetric(GetPodAllocations(respPodRes, rm.args.Namespace, rm.coreIDToNodeIDMap), rm.args.PodAllocationMetricsMaxSeries)
+	}
+
 	excludeSet := excludeList.ToMapSet()
 	zones := make(topologyv1alpha2.ZoneList, 0, len(rm.topo.Nodes))
 	// if there are no allocatable resources under a NUMA we might ended up with holes in the NRT objects.
@@ -342,12 +395,14 @@ func (rm *resourceMonitor) Scan(excludeList ResourceExclude) (ScanResponse, erro
 				// In these cases the admin knows there could be A LOT of data in the logs.
 				if isNativeResource(resName) {
 					klog.Warningf("resmon: zero capacity for native resource %q on NUMA cell %d", resName, nodeID)
+					rm.events.Warningf(k8sevents.ReasonZeroCapacity, "zero capacity for native resource %q on NUMA cell %d", resName, nodeID)
 				} else {
 					klog.V(5).Infof("resmon: zero capacity for extra resource %q on NUMA cell %d", resName, nodeID)
 				}
 			}
 			if resAlloc > resCapacity {
 				klog.Warningf("resmon: allocated more than capacity for %q on zone %q", resName.String(), zone.Name)
+				rm.events.Warningf(k8sevents.ReasonAllocatedMoreThanCapacity, "allocated more than capacity for %q on zone %q", resName.String(), zone.Name)
 				// we trust more kubelet than ourselves atm.
 				resCapacity = resAlloc
 			}
@@ -357,6 +412,7 @@ func (rm *resourceMonitor) Scan(excludeList ResourceExclude) (ScanResponse, erro
 			resAvail := resAlloc - resUsed
 			if resAvail < 0 {
 				klog.Warningf("resmon: negative size for %q on zone %q", resName.String(), zone.Name)
+				rm.events.Warningf(k8sevents.ReasonNegativeAvailable, "negative available size for %q on zone %q", resName.String(), zone.Name)
 				resAvail = 0
 			}
 
@@ -371,6 +427,20 @@ func (rm *resourceMonitor) Scan(excludeList ResourceExclude) (ScanResponse, erro
 		zones = append(zones, zone)
 	}
 	scanRes.Zones = zones
+
+	if rm.args.Fragmentation {
+		frags := ComputeFragmentation(zones)
+		fragMetrics := make([]metrics.ResourceFragmentation, 0, len(frags))
+		for _, fr := range frags {
+			fragMetrics = append(fragMetrics, metrics.ResourceFragmentation{
+				Resource:      fr.Resource,
+				LargestFree:   fr.LargestFree,
+				StrandedRatio: fr.StrandedRatio(),
+			})
+		}
+		rm.metrics.UpdateFragmentationMetric(fragMetrics)
+		scanRes.Attributes = append(scanRes.Attributes, FragmentationAttributes(frags)...)
+	}
 	return scanRes, nil
 }
 
@@ -419,11 +489,16 @@ func (rm *resourceMonitor) resUpdated(old, new interface{}) {
 func (rm *resourceMonitor) updateNodeAllocatable() error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultPodResourcesTimeout)
 	defer cancel()
+	tsBegin := time.Now()
 	allocRes, err := rm.podResCli.GetAllocatableResources(ctx, &podresourcesapi.AllocatableResourcesRequest{})
+	rm.metrics.ObservePodResourcesAPICallDuration("get_allocatable_resources", time.Since(tsBegin))
 	if err != nil {
-		metrics.UpdatePodResourceApiCallsFailuresMetric("get_allocatable_resources")
+		rm.metrics.UpdatePodResourceApiCallsFailuresMetric("get_allocatable_resources")
+		rm.health.MarkFailure(health.StagePodResources, err)
 		return err
 	}
+	rm.health.MarkSuccess(health.StagePodResources)
+	rm.debug.Record(debugstate.KeyPodResourcesAllocatable, allocRes)
 
 	allDevs := NormalizeContainerDevices(klog.V(4), allocRes.GetDevices(), allocRes.GetMemory(), allocRes.GetCpuIds(), rm.coreIDToNodeIDMap)
 	rm.nodeAllocatable = ContainerDevicesToPerNUMAResourceCounters(allDevs)
@@ -500,6 +575,33 @@ func GetAllContainerDevices(podRes []*podresourcesapi.PodResources, namespace st
 	return allCntRes
 }
 
+// GetPodAllocations reports the resources exclusively allocated to each container, per NUMA zone.
+func GetPodAllocations(podRes []*podresourcesapi.PodResources, namespace string, coreIDToNodeIDMap map[int]int) []metrics.PodAllocation {
+	var allocs []metrics.PodAllocation
+	for _, pr := range podRes {
+		// filter by namespace (if given)
+		if namespace != "" && namespace != pr.GetNamespace() {
+			continue
+		}
+		for _, cnt := range pr.GetContainers() {
+			cntDevs := NormalizeContainerDevices(klog.V(8), cnt.GetDevices(), cnt.GetMemory(), cnt.GetCpuIds(), coreIDToNodeIDMap)
+			for nodeID, resCounters := range ContainerDevicesToPerNUMAResourceCounters(cntDevs) {
+				for resName, amount := range resCounters {
+					allocs = append(allocs, metrics.PodAllocation{
+						Namespace: pr.GetNamespace(),
+						Pod:       pr.GetName(),
+						Container: cnt.GetName(),
+						Zone:      makeZoneName(nodeID),
+						Resource:  resName.String(),
+						Amount:    amount,
+					})
+				}
+			}
+		}
+	}
+	return allocs
+}
+
 // ComputePodFingerprint is deprecated and will be unexported in a future version
 func ComputePodFingerprint(podRes []*podresourcesapi.PodResources, st *podfingerprint.Status, allowFilter func(*podresourcesapi.PodResources) bool) string {
 	fp := podfingerprint.NewTracingFingerprint(len(podRes), st)
@@ -656,8 +758,7 @@ func cpuCapacity(topo *ghwtopology.Info, nodeID int) int64 {
 	return int64(logicalCoresPerNUMA)
 }
 
-func addNodeInformerEvent(c kubernetes.Interface, handler cache.ResourceEventHandlerFuncs) error {
-	factory := informers.NewSharedInformerFactory(c, 0)
+func addNodeInformerEvent(factory informers.SharedInformerFactory, handler cache.ResourceEventHandlerFuncs) error {
 	nodeInformer := factory.Core().V1().Nodes().Informer()
 	_, _ = nodeInformer.AddEventHandler(handler)
 	ctx := context.Background()
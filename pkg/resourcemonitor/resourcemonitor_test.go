@@ -17,6 +17,7 @@ limitations under the License.
 package resourcemonitor
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"sort"
@@ -38,6 +39,7 @@ import (
 
 	topologyv1alpha2 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	"github.com/k8stopologyawareschedwg/podfingerprint"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podres"
 )
 
@@ -532,7 +534,7 @@ func TestResourcesScan(t *testing.T) {
 				PodResources: []*v1.PodResources{},
 			}
 			mockPodResClient.On("List", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.ListPodResourcesRequest")).Return(resp, nil)
-			scanRes, err := resMon.Scan(ResourceExclude{}) // no pods allocation
+			scanRes, err := resMon.Scan(context.Background(), ResourceExclude{}) // no pods allocation
 			So(err, ShouldBeNil)
 
 			res := scanRes.SortedZones()
@@ -629,7 +631,7 @@ func TestResourcesScan(t *testing.T) {
 				PodResources: []*v1.PodResources{},
 			}
 			mockPodResClient.On("List", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.ListPodResourcesRequest")).Return(resp, nil)
-			scanRes, err := resMon.Scan(ResourceExclude{}) // no pods allocation
+			scanRes, err := resMon.Scan(context.Background(), ResourceExclude{}) // no pods allocation
 			So(err, ShouldBeNil)
 
 			res := scanRes.SortedZones()
@@ -825,7 +827,7 @@ func TestResourcesScan(t *testing.T) {
 			}
 
 			mockPodResClient.On("List", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.ListPodResourcesRequest")).Return(resp, nil)
-			scanRes, err := resMon.Scan(excludeList)
+			scanRes, err := resMon.Scan(context.Background(), excludeList)
 			So(err, ShouldBeNil)
 
 			res := scanRes.Zones.DeepCopy()
@@ -996,7 +998,7 @@ func TestResourcesScan(t *testing.T) {
 			}
 
 			mockPodResClient.On("List", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.ListPodResourcesRequest")).Return(resp, nil)
-			scanRes, err := resMon.Scan(excludeList)
+			scanRes, err := resMon.Scan(context.Background(), excludeList)
 			So(err, ShouldBeNil)
 
 			res := scanRes.Zones.DeepCopy()
@@ -1219,7 +1221,7 @@ func TestResourcesScan(t *testing.T) {
 			}
 
 			mockPodResClient.On("List", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.ListPodResourcesRequest")).Return(resp, nil)
-			scanRes, err := resMon.Scan(excludeList)
+			scanRes, err := resMon.Scan(context.Background(), excludeList)
 			So(err, ShouldBeNil)
 
 			res := scanRes.Zones.DeepCopy()
@@ -1349,7 +1351,7 @@ func TestResourcesScan(t *testing.T) {
 
 			mockPodResClient.On("GetAllocatableResources", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.AllocatableResourcesRequest")).Return(allocRes, nil)
 			mockPodResClient.On("List", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*v1.ListPodResourcesRequest")).Return(resp, nil)
-			scanRes, err := resMon.Scan(ResourceExclude{})
+			scanRes, err := resMon.Scan(context.Background(), ResourceExclude{})
 
 			expectedFP := "pfp0v001fe53c4dbd2c5f4a0" // pre-computed and validated manually
 			fp, ok := scanRes.Annotations[podfingerprint.Annotation]
@@ -1523,3 +1525,91 @@ var testTopology string = `{
       }
     ]
 }`
+
+func TestGetPodAllocations(t *testing.T) {
+	coreIDToNodeIDMap := map[int]int{0: 0, 1: 0, 2: 1, 3: 1}
+	podRes := []*v1.PodResources{
+		{
+			Name:      "pod-a",
+			Namespace: "ns-a",
+			Containers: []*v1.ContainerResources{
+				{
+					Name:   "cnt-0",
+					CpuIds: []int64{0, 1, 2},
+					Devices: []*v1.ContainerDevices{
+						{
+							ResourceName: "fake.io/gpu",
+							DeviceIds:    []string{"gpu-0"},
+							Topology: &v1.TopologyInfo{
+								Nodes: []*v1.NUMANode{{ID: 1}},
+							},
+						},
+					},
+				},
+				{
+					// shared CPUs only, nothing to report
+					Name: "cnt-1",
+				},
+			},
+		},
+		{
+			Name:      "pod-b",
+			Namespace: "ns-b",
+			Containers: []*v1.ContainerResources{
+				{
+					Name: "cnt-0",
+					Memory: []*v1.ContainerMemory{
+						{
+							MemoryType: "memory",
+							Size:       1024,
+							Topology: &v1.TopologyInfo{
+								Nodes: []*v1.NUMANode{{ID: 0}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	type testCase struct {
+		name      string
+		namespace string
+		expected  []metrics.PodAllocation
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name: "all namespaces",
+			expected: []metrics.PodAllocation{
+				{Namespace: "ns-a", Pod: "pod-a", Container: "cnt-0", Zone: "node-0", Resource: "cpu", Amount: 2},
+				{Namespace: "ns-a", Pod: "pod-a", Container: "cnt-0", Zone: "node-1", Resource: "cpu", Amount: 1},
+				{Namespace: "ns-a", Pod: "pod-a", Container: "cnt-0", Zone: "node-1", Resource: "fake.io/gpu", Amount: 1},
+				{Namespace: "ns-b", Pod: "pod-b", Container: "cnt-0", Zone: "node-0", Resource: "memory", Amount: 1024},
+			},
+		},
+		{
+			name:      "filtered namespace",
+			namespace: "ns-b",
+			expected: []metrics.PodAllocation{
+				{Namespace: "ns-b", Pod: "pod-b", Container: "cnt-0", Zone: "node-0", Resource: "memory", Amount: 1024},
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := GetPodAllocations(podRes, tcase.namespace, coreIDToNodeIDMap)
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].Namespace != got[j].Namespace {
+					return got[i].Namespace < got[j].Namespace
+				}
+				if got[i].Zone != got[j].Zone {
+					return got[i].Zone < got[j].Zone
+				}
+				return got[i].Resource < got[j].Resource
+			})
+			if diff := cmp.Diff(tcase.expected, got); diff != "" {
+				t.Errorf("unexpected allocations: %s", diff)
+			}
+		})
+	}
+}
@@ -1,27 +1,44 @@
 package resourcetopologyexporter
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/debugstate"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/health"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sannotations"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/notification"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podreadiness"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/tracing"
 )
 
+var errStopRequested = errors.New("stop requested")
+
 type ResourceObserver struct {
 	Infos           <-chan nrtupdater.MonitorInfo
 	resMon          resourcemonitor.ResourceMonitor
 	resourceExclude resourcemonitor.ResourceExclude
 	infoChan        chan nrtupdater.MonitorInfo
 	stopChan        chan struct{}
+	stopOnce        sync.Once
 	exposeTiming    bool
+	lastWakeup      time.Time
+	lastTimerWakeup time.Time
+	seq             uint64
+	metrics         *metrics.Metrics
+	health          *health.Tracker
+	debug           *debugstate.State
 }
 
 func NewResourceObserver(hnd resourcemonitor.Handle, args resourcemonitor.Args) (*ResourceObserver, error) {
@@ -29,67 +46,150 @@ func NewResourceObserver(hnd resourcemonitor.Handle, args resourcemonitor.Args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize ResourceMonitor: %w", err)
 	}
+	return newResourceObserverWithMonitor(resMon, args, hnd), nil
+}
 
+// newResourceObserverWithMonitor creates the observer reporting to the metrics, health and debug state
+// of the given handle, see resourcemonitor.Handle for the defaults.
+func newResourceObserverWithMonitor(resMon resourcemonitor.ResourceMonitor, args resourcemonitor.Args, hnd resourcemonitor.Handle) *ResourceObserver {
+	m := hnd.Metrics
+	if m == nil {
+		m = metrics.Default()
+	}
 	resObs := ResourceObserver{
 		resMon:          resMon,
 		resourceExclude: args.ResourceExclude,
 		stopChan:        make(chan struct{}),
 		infoChan:        make(chan nrtupdater.MonitorInfo),
 		exposeTiming:    args.ExposeTiming,
+		lastWakeup:      time.Now(),
+		metrics:         m,
+		health:          hnd.Health,
+		debug:           hnd.Debug,
 	}
 	resObs.Infos = resObs.infoChan
-	return &resObs, nil
+	return &resObs
 }
 
+// Stop requests Run to return. Safe to call multiple times.
 func (rm *ResourceObserver) Stop() {
-	rm.stopChan <- struct{}{}
+	rm.stopOnce.Do(func() {
+		close(rm.stopChan)
+	})
 }
 
-func (rm *ResourceObserver) Run(eventsChan <-chan notification.Event, condChan chan<- v1.PodCondition) {
-	lastWakeup := time.Now()
+// Run scans resources on each event until the context is cancelled or Stop is called.
+// On return, Infos is closed, so consumers can drain the data already sent.
+func (rm *ResourceObserver) Run(ctx context.Context, eventsChan <-chan notification.Event, condChan chan<- v1.PodCondition) {
+	defer close(rm.infoChan)
+	// the consumers may be gone already, so every send must give up on stop too
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-rm.stopChan:
+			cancel(errStopRequested)
+		case <-ctx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case ev := <-eventsChan:
-			var err error
-
-			monInfo := nrtupdater.MonitorInfo{Timer: ev.IsTimer()}
-
-			tsWakeupDiff := ev.Timestamp.Sub(lastWakeup)
-			lastWakeup = ev.Timestamp
-			metrics.UpdateWakeupDelayMetric(monInfo.UpdateReason(), float64(tsWakeupDiff.Milliseconds()))
-
-			tsBegin := time.Now()
-			scanRes, err := rm.resMon.Scan(rm.resourceExclude)
-			tsEnd := time.Now()
-
-			monInfo.Annotations = scanRes.Annotations
-			monInfo.Attributes = scanRes.Attributes
-			monInfo.Zones = scanRes.Zones
-
-			if rm.exposeTiming {
-				monInfo.Annotations[k8sannotations.SleepDuration] = clampTime(tsWakeupDiff.Round(time.Second)).String()
-				monInfo.Annotations[k8sannotations.UpdateInterval] = clampTime(ev.TimerInterval).String()
-			}
-
-			condStatus := v1.ConditionTrue
+			monInfo, err := rm.ScanOnce(ctx, ev)
 			if err != nil {
 				klog.Warningf("failed to scan pod resources: %v\n", err)
-				condStatus = v1.ConditionFalse
-				podreadiness.SetCondition(condChan, podreadiness.PodresourcesFetched, condStatus)
+				if !podreadiness.SetConditionWithContext(ctx, condChan, podreadiness.PodresourcesFetched, v1.ConditionFalse) {
+					rm.logStop(ctx)
+					return
+				}
 				continue
 			}
-			rm.infoChan <- monInfo
-
-			tsDiff := tsEnd.Sub(tsBegin)
-			metrics.UpdateOperationDelayMetric("podresources_scan", monInfo.UpdateReason(), float64(tsDiff.Milliseconds()))
-			podreadiness.SetCondition(condChan, podreadiness.PodresourcesFetched, condStatus)
-		case <-rm.stopChan:
-			klog.Infof("read stop at %v", time.Now())
+			select {
+			case rm.infoChan <- monInfo:
+			case <-ctx.Done():
+				rm.logStop(ctx)
+				return
+			}
+			if !podreadiness.SetConditionWithContext(ctx, condChan, podreadiness.PodresourcesFetched, v1.ConditionTrue) {
+				rm.logStop(ctx)
+				return
+			}
+		case <-ctx.Done():
+			rm.logStop(ctx)
 			return
 		}
 	}
 }
 
+func (rm *ResourceObserver) logStop(ctx context.Context) {
+	klog.Infof("read stop at %v: %v", time.Now(), context.Cause(ctx))
+}
+
+// ScanOnce runs a single scan triggered by the given event and returns the data to be published.
+// Cancelling ctx aborts the scan.
+func (rm *ResourceObserver) ScanOnce(ctx context.Context, ev notification.Event) (nrtupdater.MonitorInfo, error) {
+	rm.seq++
+	monInfo := nrtupdater.MonitorInfo{Timer: ev.IsTimer(), Sequence: rm.seq}
+	rm.metrics.UpdateScanSequenceMetric(rm.seq)
+
+	tsWakeupDiff := ev.Timestamp.Sub(rm.lastWakeup)
+	if ev.IsTimer() {
+		if !rm.lastTimerWakeup.IsZero() {
+			rm.metrics.ObserveWakeupJitter(wakeupJitter(ev.Timestamp.Sub(rm.lastTimerWakeup), ev.TimerInterval))
+		}
+		rm.lastTimerWakeup = ev.Timestamp
+	}
+	rm.lastWakeup = ev.Timestamp
+	rm.metrics.UpdateWakeupDelayMetric(monInfo.UpdateReason(), float64(tsWakeupDiff.Milliseconds()))
+
+	ctx, span := tracing.Tracer().Start(tracing.ContextWithParent(ctx, ev.SpanContext), "resourceobserver/scan",
+		trace.WithAttributes(
+			attribute.Int64(tracing.AttrSequence, int64(rm.seq)),
+			attribute.String(tracing.AttrUpdate, monInfo.UpdateReason()),
+		),
+	)
+	defer span.End()
+	monInfo.SpanContext = span.SpanContext()
+
+	tsBegin := time.Now()
+	scanRes, err := rm.resMon.Scan(ctx, rm.resourceExclude)
+	tsEnd := time.Now()
+	if err != nil {
+		tracing.SetError(span, err)
+		rm.health.MarkFailure(health.StageScan, err)
+		return monInfo, err
+	}
+	span.SetAttributes(attribute.Int(tracing.AttrZoneCount, len(scanRes.Zones)))
+	rm.health.MarkSuccess(health.StageScan)
+	rm.debug.Record(debugstate.KeyScan, scanRes)
+
+	monInfo.Annotations = scanRes.Annotations
+	monInfo.Attributes = scanRes.Attributes
+	monInfo.Zones = scanRes.Zones
+	monInfo.ScanTime = tsEnd
+	rm.metrics.UpdateScanTimestampMetric(tsEnd)
+	rm.metrics.UpdateZoneResourcesMetric(scanRes.Zones)
+
+	if rm.exposeTiming {
+		monInfo.Annotations[k8sannotations.SleepDuration] = clampTime(tsWakeupDiff.Round(time.Second)).String()
+		monInfo.Annotations[k8sannotations.UpdateInterval] = clampTime(ev.TimerInterval).String()
+	}
+
+	tsDiff := tsEnd.Sub(tsBegin)
+	rm.metrics.UpdateOperationDelayMetric("podresources_scan", monInfo.UpdateReason(), float64(tsDiff.Milliseconds()))
+	rm.metrics.ObserveScanDuration(monInfo.UpdateReason(), tsDiff)
+	return monInfo, nil
+}
+
+// wakeupJitter is how much the actual interval between two periodic wakeups differs from the expected one.
+func wakeupJitter(actual, expected time.Duration) time.Duration {
+	if actual < expected {
+		return expected - actual
+	}
+	return actual - expected
+}
+
 func clampTime(t time.Duration) time.Duration {
 	if t < 0 {
 		return 0
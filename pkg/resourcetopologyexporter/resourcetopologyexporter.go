@@ -2,7 +2,9 @@ package resourcetopologyexporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -10,6 +12,10 @@ import (
 
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
 
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/health"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/heartbeat"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sevents"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/kubeconf"
 	metricssrv "github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics/server"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/notification"
@@ -36,6 +42,24 @@ type Args struct {
 	MetricsPort            int                            `json:"metricsPort,omitempty"`
 	MetricsAddress         string                         `json:"metricsAddress,omitempty"`
 	MetricsTLSCfg          metricssrv.TLSConfig           `json:"metricsTLS,omitempty"`
+	// LeaseEnable enables the per-node Lease renewed after each successful publish.
+	// Empty namespace means the exporter pod namespace, zero duration means three sleep intervals.
+	LeaseEnable    bool          `json:"leaseEnable,omitempty"`
+	LeaseNamespace string        `json:"leaseNamespace,omitempty"`
+	LeaseDuration  time.Duration `json:"leaseDuration,omitempty"`
+	// DebugEndpoint enables the debug endpoints on the metrics server.
+	DebugEndpoint bool `json:"debugEndpoint,omitempty"`
+	// HealthStaleThreshold is the maximum age of the last success of each stage for the exporter to be ready.
+	HealthStaleThreshold time.Duration `json:"healthStaleThreshold,omitempty"`
+	// HealthAddress is the address of the plain HTTP listener serving the health endpoints. Empty disables it.
+	HealthAddress string `json:"healthAddress,omitempty"`
+	// MetricsLatencyBuckets is the comma-separated list of the latency histograms buckets, in seconds.
+	MetricsLatencyBuckets string `json:"metricsLatencyBuckets,omitempty"`
+	// EventsEnable enables the Warning events on the Node for the topology anomalies.
+	EventsEnable bool `json:"eventsEnable,omitempty"`
+	// TracingEndpoint is the host:port of the OTLP gRPC collector receiving the spans. Empty disables the tracing.
+	TracingEndpoint string `json:"tracingEndpoint,omitempty"`
+	TracingInsecure bool   `json:"tracingInsecure,omitempty"`
 }
 
 func (args Args) Clone() Args {
@@ -55,9 +79,33 @@ func (args Args) Clone() Args {
 		MetricsPort:            args.MetricsPort,
 		MetricsAddress:         args.MetricsAddress,
 		MetricsTLSCfg:          args.MetricsTLSCfg.Clone(),
+		LeaseEnable:            args.LeaseEnable,
+		LeaseNamespace:         args.LeaseNamespace,
+		LeaseDuration:          args.LeaseDuration,
+		EventsEnable:           args.EventsEnable,
+		MetricsLatencyBuckets:  args.MetricsLatencyBuckets,
+		HealthStaleThreshold:   args.HealthStaleThreshold,
+		HealthAddress:          args.HealthAddress,
+		DebugEndpoint:          args.DebugEndpoint,
+		TracingEndpoint:        args.TracingEndpoint,
+		TracingInsecure:        args.TracingInsecure,
 	}
 }
 
+// defaultLeaseDuration is used when neither the lease duration nor the sleep interval are set.
+const defaultLeaseDuration = 3 * time.Minute
+
+// shutdownGracePeriod bounds each shutdown step, and it is well within the default pod termination grace period.
+const shutdownGracePeriod = 10 * time.Second
+
+var (
+	ErrOneshotScan    = errors.New("oneshot scan failed")
+	ErrOneshotPublish = errors.New("oneshot publish failed")
+	// ErrShutdownTimeout means the pipeline was not drained within the grace period,
+	// so the updates in flight at the time of the shutdown may be lost.
+	ErrShutdownTimeout = errors.New("pipeline not drained")
+)
+
 type tmSettings struct {
 	config nrtupdater.TMConfig
 }
@@ -67,55 +115,250 @@ type Handle struct {
 	NRTCli topologyclientset.Interface
 }
 
-func Execute(hnd Handle, nrtupdaterArgs nrtupdater.Args, resourcemonitorArgs resourcemonitor.Args, rteArgs Args) error {
+// Execute runs the exporter pipeline until the context is cancelled, then shuts it down gracefully.
+func Execute(ctx context.Context, hnd Handle, nrtupdaterArgs nrtupdater.Args, resourcemonitorArgs resourcemonitor.Args, rteArgs Args) error {
 	tmConf, err := getTopologyManagerSettings(rteArgs)
 	if err != nil {
 		return err
 	}
 
-	var nodeGetter nrtupdater.NodeGetter
-	if rteArgs.AddNRTOwnerEnable {
-		nodeGetter, err = nrtupdater.NewCachedNodeGetter(hnd.ResMon.K8SCli, context.Background())
+	setupHealth(hnd, rteArgs)
+
+	var nodeGetter nrtupdater.NodeGetter = &nrtupdater.DisabledNodeGetter{}
+	var metaGetter nrtupdater.NodeGetter = &nrtupdater.DisabledNodeGetter{}
+	mirrorsNodeMetadata := nrtupdaterArgs.NodeLabels != "" || nrtupdaterArgs.NodeAnnotations != ""
+	if rteArgs.AddNRTOwnerEnable || mirrorsNodeMetadata {
+		ng, err := newNodeGetter(ctx, hnd, nrtupdaterArgs.Hostname)
 		if err != nil {
-			klog.V(2).Info("Cannot enable 'add-nrt-owner'. Unable to get node info")
-			return fmt.Errorf("Cannot enable 'add-nrt-owner'. %w", err)
+			klog.V(2).Info("Cannot watch the node. Unable to get node info")
+			return fmt.Errorf("Cannot enable 'add-nrt-owner' or the node metadata mirroring. %w", err)
 		}
-	} else {
-		nodeGetter = &nrtupdater.DisabledNodeGetter{}
+		if rteArgs.AddNRTOwnerEnable {
+			nodeGetter = ng
+		}
+		if mirrorsNodeMetadata {
+			metaGetter = ng
+		}
+	}
+
+	if rteArgs.EventsEnable {
+		rep := k8sevents.NewNodeReporter(hnd.ResMon.K8SCli, nrtupdaterArgs.Hostname)
+		defer rep.Shutdown()
+		hnd.ResMon.Events = rep
+	}
+
+	resObs, err := NewResourceObserver(hnd.ResMon, resourcemonitorArgs)
+	if err != nil {
+		return err
+	}
+
+	pub, err := nrtupdater.NewPublisher(nrtupdaterArgs, hnd.ResMon.K8SCli)
+	if err != nil {
+		return err
+	}
+
+	mpub, err := nrtupdater.NewMirrorPublisher(nrtupdaterArgs)
+	if err != nil {
+		return err
+	}
+
+	updOpts := []func(*nrtupdater.NRTUpdater){
+		nrtupdater.WithMetrics(hnd.ResMon.Metrics),
+		nrtupdater.WithHealth(hnd.ResMon.Health),
+		nrtupdater.WithDebugState(hnd.ResMon.Debug),
+		nrtupdater.WithNodeMetadataGetter(metaGetter),
+		nrtupdater.WithEventReporter(hnd.ResMon.Events),
+		nrtupdater.WithPublisher(pub),
+		nrtupdater.WithMirrorPublisher(mpub),
+	}
+	if rteArgs.LeaseEnable {
+		hb, err := newLeaseHeartbeat(hnd, nrtupdaterArgs, rteArgs)
+		if err != nil {
+			return err
+		}
+		updOpts = append(updOpts, nrtupdater.WithHeartbeat(hb))
+	}
+
+	upd, err := nrtupdater.NewNRTUpdater(nodeGetter, hnd.NRTCli, nrtupdaterArgs, tmConf.config, updOpts...)
+	if err != nil {
+		return err
+	}
+
+	if err := upd.CleanupStale(ctx); err != nil {
+		// not fatal: the stale objects don't prevent us from publishing fresh data
+		klog.Warningf("failed to cleanup stale NRT objects: %v", err)
+	}
+
+	if nrtupdaterArgs.Oneshot {
+		return executeOneshot(ctx, resObs, upd)
 	}
 
+	// the conditions must outlive the pipeline to report the shutdown, so they get their own lifecycle
+	condCtx, condCancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer condCancel()
+	condDone := make(chan struct{})
+
 	var condChan chan v1.PodCondition
+	var condIn *podreadiness.ConditionInjector
 	if rteArgs.PodReadinessEnable {
 		condChan = make(chan v1.PodCondition)
-		condIn, err := podreadiness.NewConditionInjector(hnd.ResMon.K8SCli)
+		condIn, err = podreadiness.NewConditionInjector(hnd.ResMon.K8SCli)
 		if err != nil {
 			return err
 		}
-		go condIn.Run(context.Background(), condChan)
+		go func() {
+			defer close(condDone)
+			condIn.Run(condCtx, condChan)
+		}()
+	} else {
+		close(condDone)
 	}
 
 	eventSource, err := createEventSource(&rteArgs)
 	if err != nil {
 		return err
 	}
+	defer eventSource.Close()
 
-	resObs, err := NewResourceObserver(hnd.ResMon, resourcemonitorArgs)
-	if err != nil {
-		return err
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		eventSource.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		resObs.Run(ctx, eventSource.Events(), condChan)
+	}()
+	go func() {
+		defer wg.Done()
+		upd.Run(ctx, resObs.Infos, condChan)
+	}()
+
+	<-ctx.Done()
+	klog.Infof("shutdown requested: %v", ctx.Err())
+
+	if !waitWithTimeout(&wg, shutdownGracePeriod) {
+		klog.Warningf("pipeline not drained within %v, giving up", shutdownGracePeriod)
+		resObs.Stop()
+		upd.Stop()
+		// the stuck stages may still send conditions, so condChan can't be closed:
+		// stop the injector and report the shutdown directly
+		condCancel()
+		<-condDone
+		if condIn != nil {
+			injectShutdownConditions(condIn)
+		}
+		return fmt.Errorf("%w within %v", ErrShutdownTimeout, shutdownGracePeriod)
+	}
+	klog.Infof("pipeline drained")
+
+	if condChan != nil {
+		podreadiness.SetShutdownCondition(condChan, podreadiness.PodresourcesFetched)
+		podreadiness.SetShutdownCondition(condChan, podreadiness.NodeTopologyUpdated)
+		close(condChan)
+	}
+	select {
+	case <-condDone:
+	case <-time.After(shutdownGracePeriod):
+		klog.Warningf("conditions not updated within %v, giving up", shutdownGracePeriod)
+	}
+	return nil
+}
+
+func newLeaseHeartbeat(hnd Handle, nrtupdaterArgs nrtupdater.Args, rteArgs Args) (*heartbeat.Lease, error) {
+	namespace := k8shelpers.NamespaceOrDefault(rteArgs.LeaseNamespace)
+	duration := rteArgs.LeaseDuration
+	if duration == 0 {
+		duration = 3 * rteArgs.SleepInterval
+	}
+	if duration == 0 {
+		duration = defaultLeaseDuration
+	}
+	holder := k8shelpers.NodeNameFromEnv(nrtupdaterArgs.Hostname)
+	ident := heartbeat.Identity{
+		Holder: holder,
+		Labels: map[string]string{
+			nrtupdater.LabelNodeIdentity: nrtupdater.NodeIdentity(holder),
+		},
+	}
+	klog.Infof("lease heartbeat: %s/%s duration %v", namespace, nrtupdaterArgs.Hostname, duration)
+	return heartbeat.NewLease(hnd.ResMon.K8SCli, namespace, nrtupdaterArgs.Hostname, ident, duration)
+}
+
+// newNodeGetter watches the Node owning the NRT object, which is named after it.
+// The shared informers are scoped to the node we run on, so they can be reused only if the names match.
+func newNodeGetter(ctx context.Context, hnd Handle, hostname string) (*nrtupdater.InformerNodeGetter, error) {
+	factory := hnd.ResMon.Informers
+	if factory == nil || k8shelpers.NodeNameFromEnv(hostname) != hostname {
+		factory = k8shelpers.NewSingleNodeInformerFactory(hnd.ResMon.K8SCli, hostname, 0)
+	}
+	return nrtupdater.NewInformerNodeGetter(ctx, factory, hostname)
+}
+
+func setupHealth(hnd Handle, rteArgs Args) {
+	tracker := hnd.ResMon.Health
+	if tracker == nil {
+		return
+	}
+	threshold := rteArgs.HealthStaleThreshold
+	if threshold == 0 {
+		threshold = 3 * rteArgs.SleepInterval
+	}
+	// zero selects the health package default
+	tracker.SetStaleThreshold(threshold)
+	if factory := hnd.ResMon.Informers; factory != nil {
+		tracker.AddCheck(health.StageInformers, func() error {
+			return k8shelpers.InformersSynced(factory)
+		})
+	}
+}
+
+func injectShutdownConditions(condIn *podreadiness.ConditionInjector) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	for _, condType := range []podreadiness.RTEConditionType{podreadiness.PodresourcesFetched, podreadiness.NodeTopologyUpdated} {
+		if err := condIn.Inject(ctx, podreadiness.NewShutdownCondition(condType)); err != nil {
+			klog.Warningf("failed to report the shutdown condition %s: %v", condType, err)
+		}
 	}
-	go resObs.Run(eventSource.Events(), condChan)
+}
+
+func waitWithTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
 
-	upd, err := nrtupdater.NewNRTUpdater(nodeGetter, hnd.NRTCli, nrtupdaterArgs, tmConf.config)
+// executeOneshot performs exactly one scan and one publish, reporting the outcome
+// to the caller, which is expected to exit right after.
+func executeOneshot(ctx context.Context, resObs *ResourceObserver, upd *nrtupdater.NRTUpdater) error {
+	klog.Infof("oneshot: scanning resources")
+	info, err := resObs.ScanOnce(ctx, notification.Event{Timestamp: time.Now()})
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", ErrOneshotScan, err)
 	}
-	go upd.Run(resObs.Infos, condChan)
 
-	go eventSource.Run()
+	klog.Infof("oneshot: publishing resources")
+	err = upd.Update(ctx, info)
+	if err == nil {
+		// the publishers may have deferred the delivery, but we're about to exit
+		err = upd.Flush(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOneshotPublish, err)
+	}
 
-	eventSource.Wait()  // will never return
-	eventSource.Close() // still we try to clean after ourselves :)
-	return nil          // unreachable
+	klog.Infof("oneshot: completed")
+	return nil
 }
 
 func createEventSource(rteArgs *Args) (notification.EventSource, error) {
@@ -0,0 +1,432 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcetopologyexporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8sannotations"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/k8shelpers"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/metrics"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/notification"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
+)
+
+type fakeResourceMonitor struct {
+	err error
+}
+
+func (frm *fakeResourceMonitor) Scan(_ context.Context, _ resourcemonitor.ResourceExclude) (resourcemonitor.ScanResponse, error) {
+	if frm.err != nil {
+		return resourcemonitor.ScanResponse{}, frm.err
+	}
+	return resourcemonitor.ScanResponse{
+		Zones: v1alpha2.ZoneList{
+			{
+				Name: "node-0",
+				Type: "Node",
+				Resources: v1alpha2.ResourceInfoList{
+					{
+						Name:        "cpu",
+						Capacity:    resource.MustParse("16"),
+						Allocatable: resource.MustParse("14"),
+						Available:   resource.MustParse("14"),
+					},
+				},
+			},
+		},
+		Attributes:  v1alpha2.AttributeList{},
+		Annotations: map[string]string{},
+	}, nil
+}
+
+func TestExecuteOneshot(t *testing.T) {
+	nodeName := "test-node"
+
+	type testCase struct {
+		name        string
+		scanErr     error
+		publishErr  error
+		expectedErr error
+	}
+
+	for _, tcase := range []testCase{
+		{
+			name: "success",
+		},
+		{
+			name:        "scan failure",
+			scanErr:     errors.New("fake scan error"),
+			expectedErr: ErrOneshotScan,
+		},
+		{
+			name:        "publish failure",
+			publishErr:  errors.New("fake publish error"),
+			expectedErr: ErrOneshotPublish,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			cli := fake.NewSimpleClientset()
+			if tcase.publishErr != nil {
+				cli.PrependReactor("create", "noderesourcetopologies", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tcase.publishErr
+				})
+			}
+
+			upd, err := nrtupdater.NewNRTUpdater(&nrtupdater.DisabledNodeGetter{}, cli, nrtupdater.Args{Hostname: nodeName, Oneshot: true}, nrtupdater.TMConfig{})
+			if err != nil {
+				t.Fatalf("failed to create NRT updater: %v", err)
+			}
+			resObs := newResourceObserverWithMonitor(&fakeResourceMonitor{err: tcase.scanErr}, resourcemonitor.Args{}, resourcemonitor.Handle{})
+
+			err = executeOneshot(context.Background(), resObs, upd)
+			if tcase.expectedErr != nil {
+				if !errors.Is(err, tcase.expectedErr) {
+					t.Fatalf("unexpected error: got %v expected %v", err, tcase.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			nrt, err := cli.TopologyV1alpha2().NodeResourceTopologies().Get(context.Background(), nodeName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get the published NRT: %v", err)
+			}
+			if len(nrt.Zones) != 1 {
+				t.Errorf("unexpected zones: %v", nrt.Zones)
+			}
+		})
+	}
+}
+
+func TestPipelineDrainsOnShutdown(t *testing.T) {
+	nodeName := "test-node"
+	cli := fake.NewSimpleClientset()
+	upd, err := nrtupdater.NewNRTUpdater(&nrtupdater.DisabledNodeGetter{}, cli, nrtupdater.Args{Hostname: nodeName}, nrtupdater.TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create NRT updater: %v", err)
+	}
+	resObs := newResourceObserverWithMonitor(&fakeResourceMonitor{}, resourcemonitor.Args{}, resourcemonitor.Handle{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	evChan := make(chan notification.Event)
+	obsDone := make(chan struct{})
+	updDone := make(chan struct{})
+	go func() {
+		defer close(obsDone)
+		resObs.Run(ctx, evChan, nil)
+	}()
+	go func() {
+		defer close(updDone)
+		upd.Run(ctx, resObs.Infos, nil)
+	}()
+
+	evChan <- notification.Event{Timestamp: time.Now()}
+	cancel()
+
+	for _, done := range []<-chan struct{}{obsDone, updDone} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("pipeline did not shut down")
+		}
+	}
+
+	_, err = cli.TopologyV1alpha2().NodeResourceTopologies().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("in-flight update was not drained: %v", err)
+	}
+}
+
+func TestObserverStopsWithoutConsumers(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		condChan chan corev1.PodCondition
+	}{
+		{name: "infos"},
+		// the infos are consumed below, the conditions are not
+		{name: "conditions", condChan: make(chan corev1.PodCondition)},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			resObs := newResourceObserverWithMonitor(&fakeResourceMonitor{}, resourcemonitor.Args{}, resourcemonitor.Handle{})
+			evChan := make(chan notification.Event, 1)
+			obsDone := make(chan struct{})
+			go func() {
+				defer close(obsDone)
+				resObs.Run(context.Background(), evChan, tcase.condChan)
+			}()
+
+			evChan <- notification.Event{Timestamp: time.Now()}
+			if tcase.condChan != nil {
+				<-resObs.Infos
+			}
+			resObs.Stop()
+
+			select {
+			case <-obsDone:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("observer blocked on a send after stop")
+			}
+		})
+	}
+}
+
+func TestScanSequence(t *testing.T) {
+	resMon := &fakeResourceMonitor{}
+	resObs := newResourceObserverWithMonitor(resMon, resourcemonitor.Args{}, resourcemonitor.Handle{})
+	cli := fake.NewSimpleClientset()
+	upd, err := nrtupdater.NewNRTUpdater(&nrtupdater.DisabledNodeGetter{}, cli, nrtupdater.Args{Hostname: "test-node"}, nrtupdater.TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create the updater: %v", err)
+	}
+
+	// a failed scan is a skipped update, which must be visible as a gap in the sequence
+	expectedSeqs := []string{"1", "", "3"}
+	for idx, scanErr := range []error{nil, errors.New("fake scan failure"), nil} {
+		resMon.err = scanErr
+		info, err := resObs.ScanOnce(context.Background(), notification.Event{Timestamp: time.Now()})
+		if scanErr != nil {
+			if err == nil {
+				t.Fatalf("scan %d unexpectedly succeeded", idx)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("scan %d failed: %v", idx, err)
+		}
+		if info.ScanTime.IsZero() {
+			t.Errorf("scan %d missing the timestamp", idx)
+		}
+		err = upd.Update(context.Background(), info)
+		if err != nil {
+			t.Fatalf("update %d failed: %v", idx, err)
+		}
+
+		nrt, err := cli.TopologyV1alpha2().NodeResourceTopologies().Get(context.Background(), "test-node", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get the NRT object: %v", err)
+		}
+		if got := nrt.Annotations[k8sannotations.ScanSequence]; got != expectedSeqs[idx] {
+			t.Errorf("scan %d unexpected sequence: got %q expected %q", idx, got, expectedSeqs[idx])
+		}
+		ts, err := time.Parse(time.RFC3339Nano, nrt.Annotations[k8sannotations.ScanTimestamp])
+		if err != nil {
+			t.Fatalf("scan %d malformed timestamp: %v", idx, err)
+		}
+		if !ts.Equal(info.ScanTime) {
+			t.Errorf("scan %d unexpected timestamp: got %v expected %v", idx, ts, info.ScanTime)
+		}
+	}
+}
+
+// blockingResourceMonitor scans until the context is done
+type blockingResourceMonitor struct{}
+
+func (brm blockingResourceMonitor) Scan(ctx context.Context, _ resourcemonitor.ResourceExclude) (resourcemonitor.ScanResponse, error) {
+	<-ctx.Done()
+	return resourcemonitor.ScanResponse{}, ctx.Err()
+}
+
+func TestScanOnceCancelled(t *testing.T) {
+	resObs := newResourceObserverWithMonitor(blockingResourceMonitor{}, resourcemonitor.Args{}, resourcemonitor.Handle{})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := resObs.ScanOnce(ctx, notification.Event{Timestamp: time.Now()})
+		errCh <- err
+	}()
+	cancel()
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the scan ignored the cancellation")
+	}
+}
+
+func TestWakeupJitter(t *testing.T) {
+	m, err := metrics.New("node-a", nil)
+	if err != nil {
+		t.Fatalf("failed to create the metrics: %v", err)
+	}
+	resObs := newResourceObserverWithMonitor(&fakeResourceMonitor{}, resourcemonitor.Args{}, resourcemonitor.Handle{Metrics: m})
+	interval := 10 * time.Second
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// the first periodic wakeup has no previous one to compare against.
+	// The others are respectively 200ms late and 500ms early.
+	for _, offset := range []time.Duration{0, interval + 200*time.Millisecond, 2*interval - 300*time.Millisecond} {
+		_, err = resObs.ScanOnce(context.Background(), notification.Event{Timestamp: ts.Add(offset), TimerInterval: interval})
+		if err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+	}
+	// reactive wakeups don't contribute
+	_, err = resObs.ScanOnce(context.Background(), notification.Event{Timestamp: ts.Add(2 * interval)})
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+
+	hist := &dto.Metric{}
+	err = m.WakeupJitter.WithLabelValues("node-a").(prometheus.Metric).Write(hist)
+	if err != nil {
+		t.Fatalf("failed to read the jitter histogram: %v", err)
+	}
+	if got := hist.GetHistogram().GetSampleCount(); got != 2 {
+		t.Errorf("unexpected jitter samples: got %d expected 2", got)
+	}
+	if got := hist.GetHistogram().GetSampleSum(); got < 0.699 || got > 0.701 {
+		t.Errorf("unexpected jitter sum: got %v expected 0.7", got)
+	}
+}
+
+func TestTraceLinksCycle(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	es, err := notification.NewUnlimitedEventSource()
+	if err != nil {
+		t.Fatalf("failed to create the event source: %v", err)
+	}
+	defer es.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	go es.Run(ctx)
+	ev := <-es.Events()
+	cancel()
+	es.Wait()
+
+	resObs := newResourceObserverWithMonitor(&fakeResourceMonitor{}, resourcemonitor.Args{}, resourcemonitor.Handle{})
+	info, err := resObs.ScanOnce(context.Background(), ev)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	upd, err := nrtupdater.NewNRTUpdater(&nrtupdater.DisabledNodeGetter{}, fake.NewSimpleClientset(), nrtupdater.Args{Hostname: "test-node"}, nrtupdater.TMConfig{})
+	if err != nil {
+		t.Fatalf("failed to create the updater: %v", err)
+	}
+	err = upd.Update(context.Background(), info)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		spans[span.Name()] = span
+	}
+	// each span must be the child of the previous one
+	chain := []string{"notification/event", "resourceobserver/scan", "nrtupdater/update", "nrtupdater/create"}
+	for idx, name := range chain {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("missing span %q, got %v", name, spans)
+		}
+		if span.SpanContext().TraceID() != ev.SpanContext.TraceID() {
+			t.Errorf("span %q not in the event trace", name)
+		}
+		if idx == 0 {
+			continue
+		}
+		if span.Parent().SpanID() != spans[chain[idx-1]].SpanContext().SpanID() {
+			t.Errorf("span %q is not the child of %q", name, chain[idx-1])
+		}
+	}
+}
+
+func TestInformersScopedByNodeName(t *testing.T) {
+	// the NRT name is overridden, so it differs from the node we run on
+	t.Setenv("NODE_NAME", "real-node")
+	hostname := "nrt-name"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cli := k8sfake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "real-node"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: hostname}},
+	)
+	factory := k8shelpers.NewNodeScopedInformerFactory(cli, k8shelpers.NodeNameFromEnv(hostname), 0)
+	factory.Core().V1().Nodes().Informer()
+	factory.Core().V1().Pods().Informer()
+	if err := k8shelpers.StartAndSync(ctx, factory); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	hnd := Handle{
+		ResMon: resourcemonitor.Handle{
+			K8SCli:    cli,
+			Informers: factory,
+		},
+	}
+	ng, err := newNodeGetter(ctx, hnd, hostname)
+	if err != nil {
+		t.Fatalf("failed to create the node getter: %v", err)
+	}
+	node, err := ng.Get(ctx, hostname, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the NRT owner node: %v", err)
+	}
+	if node.Name != hostname {
+		t.Errorf("unexpected owner node: %q", node.Name)
+	}
+
+	got := make(map[string]int)
+	for _, action := range cli.Actions() {
+		listAction, ok := action.(k8stesting.ListAction)
+		if !ok {
+			continue
+		}
+		got[action.GetResource().Resource+" "+listAction.GetListRestrictions().Fields.String()]++
+	}
+	// the resource monitor and the terminal pods filter need the node we run on, the owner references the NRT node
+	for _, expected := range []string{
+		"nodes metadata.name=real-node",
+		"pods spec.nodeName=real-node",
+		"nodes metadata.name=" + hostname,
+	} {
+		if got[expected] == 0 {
+			t.Errorf("missing list %q, got %v", expected, got)
+		}
+	}
+	if got["pods spec.nodeName="+hostname] != 0 {
+		t.Errorf("pods scoped by the NRT name: %v", got)
+	}
+}
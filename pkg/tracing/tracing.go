@@ -0,0 +1,128 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing instruments the update cycle with OpenTelemetry spans.
+// Each notification event starts a trace, which the scan, the podresources calls
+// and the NRT writes triggered by that event join, so a pod admission can be
+// correlated with the exporter cycle which reported it.
+// Unless an endpoint is configured, the spans are not recorded nor exported.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/version"
+)
+
+const (
+	TracerName = "github.com/k8stopologyawareschedwg/resource-topology-exporter"
+)
+
+const (
+	AttrTrigger     = "rte.trigger"
+	AttrSequence    = "rte.sequence"
+	AttrUpdate      = "rte.update_reason"
+	AttrOperation   = "rte.operation"
+	AttrPublisher   = "rte.publisher"
+	AttrPending     = "rte.publish.pending"
+	AttrLayer       = "rte.podresources.layer"
+	AttrPodCount    = "rte.podresources.pods"
+	AttrZoneCount   = "rte.zones"
+	AttrRPCSystem   = "rpc.system"
+	AttrRPCService  = "rpc.service"
+	AttrRPCMethod   = "rpc.method"
+	AttrNodeName    = "k8s.node.name"
+	AttrServiceName = "service.name"
+	AttrServiceVer  = "service.version"
+)
+
+type Config struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. Empty disables the tracing.
+	Endpoint string
+	// Insecure disables the TLS towards the collector.
+	Insecure bool
+	NodeName string
+}
+
+// Setup installs the global tracer provider, exporting the spans to the configured endpoint.
+// If the endpoint is empty the no-op provider is kept. The returned function flushes
+// the pending spans and stops the exporter; it is always safe to call.
+func Setup(ctx context.Context, conf Config) (func(context.Context) error, error) {
+	if conf.Endpoint == "" {
+		klog.Infof("tracing: disabled")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(conf.Endpoint),
+	}
+	if conf.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exp, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String(AttrServiceName, version.ProgramName),
+		attribute.String(AttrServiceVer, version.Get()),
+		attribute.String(AttrNodeName, conf.NodeName),
+	)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	klog.Infof("tracing: exporting to %q (insecure=%v)", conf.Endpoint, conf.Insecure)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer of the exporter, backed by the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// ContextWithParent returns a context carrying the given span context, so spans
+// started from it join its trace. Invalid span contexts leave ctx untouched.
+func ContextWithParent(ctx context.Context, parent trace.SpanContext) context.Context {
+	if !parent.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, parent)
+}
+
+// SetError marks the span as failed if err is not nil.
+func SetError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupDisabled(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if otel.GetTracerProvider() != prev {
+		t.Errorf("the tracer provider changed with tracing disabled")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+}
+
+func TestContextWithParent(t *testing.T) {
+	ctx := context.Background()
+	if got := ContextWithParent(ctx, trace.SpanContext{}); got != ctx {
+		t.Errorf("invalid parent should leave the context untouched")
+	}
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	_, parent := tp.Tracer(TracerName).Start(ctx, "parent")
+	parent.End()
+	_, child := tp.Tracer(TracerName).Start(ContextWithParent(ctx, parent.SpanContext()), "child")
+	child.End()
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Errorf("child not linked to the parent: %v", spans[1].Parent())
+	}
+	if spans[1].SpanContext().TraceID() != spans[0].SpanContext().TraceID() {
+		t.Errorf("child in a different trace")
+	}
+}
+
+func TestSetError(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	for _, err := range []error{nil, errors.New("fake failure")} {
+		_, span := tp.Tracer(TracerName).Start(context.Background(), "op")
+		SetError(span, err)
+		span.End()
+	}
+
+	spans := sr.Ended()
+	if got := spans[0].Status().Code; got != codes.Unset {
+		t.Errorf("unexpected status without error: %v", got)
+	}
+	if got := spans[1].Status().Code; got != codes.Error {
+		t.Errorf("unexpected status with error: %v", got)
+	}
+	if len(spans[1].Events()) != 1 {
+		t.Errorf("error not recorded: %v", spans[1].Events())
+	}
+}
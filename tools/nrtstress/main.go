@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"time"
 
@@ -60,5 +61,5 @@ func main() {
 	if err != nil {
 		klog.Fatalf("failed to create a noderesourcetopology updater: %v", err)
 	}
-	upd.Run(gen.Infos, nil)
+	upd.Run(context.Background(), gen.Infos, nil)
 }